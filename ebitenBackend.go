@@ -0,0 +1,186 @@
+package nimgobus
+
+import (
+	"image"
+	"io"
+	"log"
+	"time"
+
+	"github.com/adamstimb/nimgobus/subbios"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// ebitenKeys maps the subbios keys to the Ebiten keys that press them.
+var ebitenKeys = map[subbios.Key][]ebiten.Key{
+	subbios.KeyEnter:      {ebiten.KeyEnter, ebiten.KeyNumpadEnter},
+	subbios.KeyBackspace:  {ebiten.KeyBackspace},
+	subbios.KeyLeft:       {ebiten.KeyLeft},
+	subbios.KeyRight:      {ebiten.KeyRight},
+	subbios.KeyUp:         {ebiten.KeyUp},
+	subbios.KeyDown:       {ebiten.KeyDown},
+	subbios.KeyHome:       {ebiten.KeyHome},
+	subbios.KeyEnd:        {ebiten.KeyEnd},
+	subbios.KeyTab:        {ebiten.KeyTab},
+	subbios.KeyF1:         {ebiten.KeyF1},
+	subbios.KeyF2:         {ebiten.KeyF2},
+	subbios.KeyF3:         {ebiten.KeyF3},
+	subbios.KeyF4:         {ebiten.KeyF4},
+	subbios.KeyF5:         {ebiten.KeyF5},
+	subbios.KeyF6:         {ebiten.KeyF6},
+	subbios.KeyF7:         {ebiten.KeyF7},
+	subbios.KeyF8:         {ebiten.KeyF8},
+	subbios.KeyF9:         {ebiten.KeyF9},
+	subbios.KeyF10:        {ebiten.KeyF10},
+	subbios.KeyF11:        {ebiten.KeyF11},
+	subbios.KeyF12:        {ebiten.KeyF12},
+	subbios.KeyControl:    {ebiten.KeyControlLeft, ebiten.KeyControlRight},
+	subbios.KeyShift:      {ebiten.KeyShiftLeft, ebiten.KeyShiftRight},
+	subbios.KeyC:          {ebiten.KeyC},
+	subbios.KeyScrollLock: {ebiten.KeyScrollLock},
+	subbios.KeyEscape:     {ebiten.KeyEscape},
+	subbios.KeyPageUp:     {ebiten.KeyPageUp},
+	subbios.KeyPageDown:   {ebiten.KeyPageDown},
+	subbios.KeyInsert:     {ebiten.KeyInsert},
+	subbios.KeyDelete:     {ebiten.KeyDelete},
+	subbios.KeyD:          {ebiten.KeyD},
+	subbios.KeyAlt:        {ebiten.KeyAltLeft}, // ALT GR types chars
+	subbios.KeyA:          {ebiten.KeyA},
+	subbios.KeyB:          {ebiten.KeyB},
+	subbios.KeyE:          {ebiten.KeyE},
+	subbios.KeyF:          {ebiten.KeyF},
+	subbios.KeyG:          {ebiten.KeyG},
+	subbios.KeyH:          {ebiten.KeyH},
+	subbios.KeyI:          {ebiten.KeyI},
+	subbios.KeyJ:          {ebiten.KeyJ},
+	subbios.KeyK:          {ebiten.KeyK},
+	subbios.KeyL:          {ebiten.KeyL},
+	subbios.KeyM:          {ebiten.KeyM},
+	subbios.KeyN:          {ebiten.KeyN},
+	subbios.KeyO:          {ebiten.KeyO},
+	subbios.KeyP:          {ebiten.KeyP},
+	subbios.KeyQ:          {ebiten.KeyQ},
+	subbios.KeyR:          {ebiten.KeyR},
+	subbios.KeyS:          {ebiten.KeyS},
+	subbios.KeyT:          {ebiten.KeyT},
+	subbios.KeyU:          {ebiten.KeyU},
+	subbios.KeyV:          {ebiten.KeyV},
+	subbios.KeyW:          {ebiten.KeyW},
+	subbios.KeyX:          {ebiten.KeyX},
+	subbios.KeyY:          {ebiten.KeyY},
+	subbios.KeyZ:          {ebiten.KeyZ},
+	subbios.Key0:          {ebiten.KeyDigit0, ebiten.KeyNumpad0},
+	subbios.Key1:          {ebiten.KeyDigit1, ebiten.KeyNumpad1},
+	subbios.Key2:          {ebiten.KeyDigit2, ebiten.KeyNumpad2},
+	subbios.Key3:          {ebiten.KeyDigit3, ebiten.KeyNumpad3},
+	subbios.Key4:          {ebiten.KeyDigit4, ebiten.KeyNumpad4},
+	subbios.Key5:          {ebiten.KeyDigit5, ebiten.KeyNumpad5},
+	subbios.Key6:          {ebiten.KeyDigit6, ebiten.KeyNumpad6},
+	subbios.Key7:          {ebiten.KeyDigit7, ebiten.KeyNumpad7},
+	subbios.Key8:          {ebiten.KeyDigit8, ebiten.KeyNumpad8},
+	subbios.Key9:          {ebiten.KeyDigit9, ebiten.KeyNumpad9},
+	subbios.KeySpace:      {ebiten.KeySpace},
+}
+
+// ebitenBackend is the default subbios.Backend.  It draws the monitor on an Ebiten
+// image, gets keyboard and mouse input from Ebiten and plays sound through Ebiten
+// audio.
+type ebitenBackend struct {
+	monitor *ebiten.Image
+	player  *audio.Player // Hang on to this or the sound stops
+}
+
+// newEbitenBackend returns an ebitenBackend with a fresh monitor image.
+func newEbitenBackend() *ebitenBackend {
+	return &ebitenBackend{monitor: ebiten.NewImage(subbios.MonitorWidth, subbios.MonitorHeight)}
+}
+
+// Render writes the monitor image to the Ebiten monitor.
+func (b *ebitenBackend) Render(monitor *image.RGBA) {
+	b.monitor.WritePixels(monitor.Pix)
+}
+
+// PlayAudio plays the sound chip's stream through Ebiten audio.  The buffer is kept
+// small so sounds start promptly when the registers are changed.
+func (b *ebitenBackend) PlayAudio(stream io.Reader) error {
+	ctx := audio.CurrentContext()
+	if ctx == nil {
+		ctx = audio.NewContext(subbios.SampleRate)
+	}
+	player, err := ctx.NewPlayer(stream)
+	if err != nil {
+		log.Printf("Couldn't start sound: %v", err)
+		return err
+	}
+	player.SetBufferSize(50 * time.Millisecond)
+	player.Play()
+	b.player = player
+	return nil
+}
+
+// AppendInputChars appends the runes typed since the last update to runes.
+func (b *ebitenBackend) AppendInputChars(runes []rune) []rune {
+	return ebiten.AppendInputChars(runes)
+}
+
+// KeyPressDuration returns the longest duration any of the Ebiten keys mapped to key
+// have been held for.
+func (b *ebitenBackend) KeyPressDuration(key subbios.Key) int {
+	d := 0
+	for _, k := range ebitenKeys[key] {
+		if kd := inpututil.KeyPressDuration(k); kd > d {
+			d = kd
+		}
+	}
+	return d
+}
+
+// CursorPosition returns the mouse position scaled down to the monitor image.
+func (b *ebitenBackend) CursorPosition() (x, y int) {
+	x, y = ebiten.CursorPosition()
+	scale, offsetX, offsetY := b.getScale()
+	x = int(float64(x)/scale) - int(offsetX)
+	y = int(float64(y)/scale) - int(offsetY)
+	return x, y
+}
+
+// IsMouseButtonPressed returns true if button is held down.
+func (b *ebitenBackend) IsMouseButtonPressed(button subbios.MouseButton) bool {
+	switch button {
+	case subbios.MouseButtonLeft:
+		return ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft)
+	case subbios.MouseButtonRight:
+		return ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight)
+	}
+	return false
+}
+
+// getScale returns the scale and x, y offset of the application screen
+func (b *ebitenBackend) getScale() (scale, offsetX, offsetY float64) {
+	// Get Nimbus monitor screen size
+	monitorWidth, monitorHeight := b.monitor.Size()
+
+	// Get ebiten window size so we can scale the Nimbus screen up or down
+	// but if (0, 0) is returned we're not running on a desktop so don't do any scaling
+	windowWidth, windowHeight := ebiten.WindowSize()
+
+	// Calculate aspect ratios of Nimbus monitor and ebiten screen
+	monitorRatio := float64(monitorWidth) / float64(monitorHeight)
+	windowRatio := float64(windowWidth) / float64(windowHeight)
+
+	// If windowRatio > monitorRatio then clamp monitorHeight to windowHeight otherwise
+	// clamp monitorWidth to screenWidth
+	switch {
+	case windowRatio > monitorRatio:
+		scale = float64(windowHeight) / float64(monitorHeight)
+		offsetX = (float64(windowWidth) - float64(monitorWidth)*scale) / 2
+		offsetY = 0
+	case windowRatio <= monitorRatio:
+		scale = float64(windowWidth) / float64(monitorWidth)
+		offsetX = 0
+		offsetY = (float64(windowHeight) - float64(monitorHeight)*scale) / 2
+	}
+
+	return scale, offsetX, offsetY
+}
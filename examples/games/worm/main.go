@@ -9,8 +9,8 @@ import (
 
 	"github.com/adamstimb/nimgobus"
 	"github.com/adamstimb/nimgobus/examples/games/worm/queue"
+	"github.com/adamstimb/nimgobus/subbios"
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
 type Game struct {
@@ -38,15 +38,6 @@ func NewGame() *Game {
 	return game
 }
 
-func isAnyKeyPressed() bool {
-	for k := ebiten.Key(0); k <= ebiten.KeyMax; k++ {
-		if ebiten.IsKeyPressed(k) {
-			return true
-		}
-	}
-	return false
-}
-
 func (g *Game) Update() error {
 	if g.launch == 0 {
 		go App(g) // Launch the Nimbus app on first iteration
@@ -55,51 +46,27 @@ func (g *Game) Update() error {
 	g.Nimbus.Update() // Update the app on all subsequent iterations
 
 	// Synchronise keypresses to wormCounter so the worm game loop doesn't miss anything
-	//var anyKey bool
-	//var key ebiten.Key
-
-	// Handle direction
-	if inpututil.KeyPressDuration(ebiten.KeyA) > 0 {
-		g.keyA = true
-		g.keyS = false
-		g.keyK = false
-		g.keyM = false
-		g.keyD = false
-	}
-	if inpututil.KeyPressDuration(ebiten.KeyS) > 0 {
-		g.keyA = false
-		g.keyS = true
-		g.keyK = false
-		g.keyM = false
-		g.keyD = false
-	}
-	if inpututil.KeyPressDuration(ebiten.KeyK) > 0 {
-		g.keyA = false
-		g.keyS = false
-		g.keyK = true
-		g.keyM = false
-		g.keyD = false
-	}
-	if inpututil.KeyPressDuration(ebiten.KeyM) > 0 {
-		g.keyA = false
-		g.keyS = false
-		g.keyK = false
-		g.keyM = true
-		g.keyD = false
-	}
-	if inpututil.KeyPressDuration(ebiten.KeyD) > 0 {
-		g.keyA = false
-		g.keyS = false
-		g.keyK = false
-		g.keyM = false
-		g.keyD = true
-	}
-	// Handle other control inputs
-	if inpututil.KeyPressDuration(ebiten.KeyEscape) > 0 && inpututil.KeyPressDuration(ebiten.KeyEscape) < 2 {
-		g.keyEscape = true
-	}
-	if isAnyKeyPressed() {
+	for {
+		e, ok := g.Subbios.TKeyboard.NextEvent()
+		if !ok {
+			break
+		}
+		if e.Action != subbios.KeyPressed {
+			continue
+		}
 		g.anyKey = true
+		// Handle direction
+		switch e.Key {
+		case subbios.KeyA, subbios.KeyS, subbios.KeyK, subbios.KeyM, subbios.KeyD:
+			g.keyA = e.Key == subbios.KeyA
+			g.keyS = e.Key == subbios.KeyS
+			g.keyK = e.Key == subbios.KeyK
+			g.keyM = e.Key == subbios.KeyM
+			g.keyD = e.Key == subbios.KeyD
+		case subbios.KeyEscape:
+			// Handle other control inputs
+			g.keyEscape = true
+		}
 	}
 
 	return nil
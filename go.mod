@@ -7,9 +7,11 @@ require (
 	github.com/hajimehoshi/ebiten/v2 v2.6.3
 	github.com/llgcode/draw2d v0.0.0-20231212091825-f55e0c776b44
 	github.com/shirou/gopsutil v3.21.11+incompatible
+	golang.org/x/sys v0.15.0
 )
 
 require (
+	github.com/ebitengine/oto/v3 v3.1.0 // indirect
 	github.com/ebitengine/purego v0.5.1 // indirect
 	github.com/elastic/go-windows v1.0.1 // indirect
 	github.com/go-ole/go-ole v1.3.0 // indirect
@@ -25,6 +27,5 @@ require (
 	golang.org/x/image v0.14.0 // indirect
 	golang.org/x/mobile v0.0.0-20231127183840-76ac6878050a // indirect
 	golang.org/x/sync v0.5.0 // indirect
-	golang.org/x/sys v0.15.0 // indirect
 	howett.net/plist v1.0.1 // indirect
 )
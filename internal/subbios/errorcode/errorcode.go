@@ -1,16 +0,0 @@
-// Package errorcode defines constants for the subbios error codes.
-package errorcode
-
-const (
-	EOk = iota
-	ETypeDoesntExist
-	EDriverDoesntExist
-	EFuncNotImplemented
-	EDiffSegs
-	EUnderflow
-	EOverflow
-	ENotANumber
-	ENotInitialized
-	EAlreadyOn
-	EInvalidParameter
-)
@@ -2,7 +2,7 @@
 package nimgobus
 
 import (
-	"github.com/adamstimb/nimgobus/internal/subbios"
+	"github.com/adamstimb/nimgobus/subbios"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
@@ -14,8 +14,15 @@ type Nimbus struct {
 
 // Initializes nimgobus.
 func (n *Nimbus) Init() {
-	n.Subbios.Init()
-	n.Monitor = n.Subbios.Monitor
+	b := newEbitenBackend()
+	n.Subbios.InitWithBackend(b)
+	n.Monitor = b.monitor
+}
+
+// InitWithBackend initializes nimgobus with another rendering backend, e.g. a
+// subbios.ImageBackend for running headless.  Monitor will be nil.
+func (n *Nimbus) InitWithBackend(b subbios.Backend) {
+	n.Subbios.InitWithBackend(b)
 }
 
 // Update needs to be called on each ebiten update call.
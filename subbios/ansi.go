@@ -0,0 +1,275 @@
+package subbios
+
+import (
+	"image/color"
+
+	"github.com/adamstimb/nimgobus/subbios/colour"
+)
+
+// ansiColours are the 16 standard ANSI colours (30-37 then the bright 90-97), as
+// rendered by a VGA text console.
+var ansiColours = [16]color.RGBA{
+	{0x00, 0x00, 0x00, 0xff}, // black
+	{0xaa, 0x00, 0x00, 0xff}, // red
+	{0x00, 0xaa, 0x00, 0xff}, // green
+	{0xaa, 0x55, 0x00, 0xff}, // yellow (well, brown)
+	{0x00, 0x00, 0xaa, 0xff}, // blue
+	{0xaa, 0x00, 0xaa, 0xff}, // magenta
+	{0x00, 0xaa, 0xaa, 0xff}, // cyan
+	{0xaa, 0xaa, 0xaa, 0xff}, // white (well, light grey)
+	{0x55, 0x55, 0x55, 0xff}, // bright black
+	{0xff, 0x55, 0x55, 0xff}, // bright red
+	{0x55, 0xff, 0x55, 0xff}, // bright green
+	{0xff, 0xff, 0x55, 0xff}, // bright yellow
+	{0x55, 0x55, 0xff, 0xff}, // bright blue
+	{0xff, 0x55, 0xff, 0xff}, // bright magenta
+	{0x55, 0xff, 0xff, 0xff}, // bright cyan
+	{0xff, 0xff, 0xff, 0xff}, // bright white
+}
+
+// setANSIMode switches between Nimbus (n=0) and ANSI (n=1) escape sequences.
+func (c *console) setANSIMode(n int) {
+	if n == 0 {
+		c.ansiMode = false
+	}
+	if n == 1 {
+		c.ansiMode = true
+	}
+}
+
+// setANSIGraphicsRendition is setGraphicsRendition for standard ECMA-48 SGR
+// parameters, with the colours mapped onto the nearest logical colours.
+func (c *console) setANSIGraphicsRendition(params []int) {
+	// ESC[m is the same as ESC[0m
+	if params[0] == -1 {
+		params[0] = 0
+	}
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == -1:
+			continue
+		case c.setAttribute(p):
+			continue
+		case p >= 30 && p <= 37:
+			c.penColour = c.nearestLogicalColour(ansiColours[p-30])
+		case p >= 90 && p <= 97:
+			c.penColour = c.nearestLogicalColour(ansiColours[p-90+8])
+		case p >= 40 && p <= 47:
+			c.paperColour = c.nearestLogicalColour(ansiColours[p-40])
+		case p >= 100 && p <= 107:
+			c.paperColour = c.nearestLogicalColour(ansiColours[p-100+8])
+		case p == 39:
+			c.penColour = c.defaultPenColour()
+		case p == 49:
+			c.paperColour = 0
+		case p == 38 || p == 48:
+			// 256 colours (38;5;n) or true colour (38;2;r;g;b)
+			rgb, n, ok := extendedColour(params[i+1:])
+			i += n
+			if !ok {
+				continue
+			}
+			if p == 38 {
+				c.penColour = c.nearestLogicalColour(rgb)
+			} else {
+				c.paperColour = c.nearestLogicalColour(rgb)
+			}
+		}
+	}
+}
+
+// extendedColour returns the colour given by the parameters after a 38 or 48 in an
+// SGR, and how many of them it used.
+func extendedColour(params []int) (rgb color.RGBA, used int, ok bool) {
+	if len(params) >= 2 && params[0] == 5 {
+		n := params[1]
+		switch {
+		case n < 0 || n > 255:
+			return rgb, 2, false
+		case n < 16:
+			return ansiColours[n], 2, true
+		case n < 232:
+			// 6x6x6 colour cube
+			levels := [6]uint8{0, 95, 135, 175, 215, 255}
+			n -= 16
+			return color.RGBA{levels[n/36], levels[(n/6)%6], levels[n%6], 0xff}, 2, true
+		default:
+			// Greys
+			g := uint8(8 + (n-232)*10)
+			return color.RGBA{g, g, g, 0xff}, 2, true
+		}
+	}
+	if len(params) >= 4 && params[0] == 2 {
+		for _, v := range params[1:4] {
+			if v < 0 || v > 255 {
+				return rgb, 4, false
+			}
+		}
+		return color.RGBA{uint8(params[1]), uint8(params[2]), uint8(params[3]), 0xff}, 4, true
+	}
+	return rgb, 0, false
+}
+
+// nearestLogicalColour returns the logical colour whose physical colour in the
+// console's colour lookup table is closest to rgb.  Flashing colours are left out
+// because nobody wants their ls output flashing.
+func (c *console) nearestLogicalColour(rgb color.RGBA) int {
+	var clt [][3]int
+	if c.v.screenWidth == 40 {
+		clt = c.lowResColourLookupTable[:]
+	} else {
+		clt = c.hiResColourLookupTable[:]
+	}
+	best, bestDistance := 0, -1
+	for logical, e := range clt {
+		if e[2] != 0 {
+			continue
+		}
+		p := colour.PhysicalColours[e[0]]
+		dr, dg, db := int(p.R)-int(rgb.R), int(p.G)-int(rgb.G), int(p.B)-int(rgb.B)
+		distance := dr*dr + dg*dg + db*db
+		if bestDistance == -1 || distance < bestDistance {
+			best, bestDistance = logical, distance
+		}
+	}
+	return best
+}
+
+// setPrivateMode handles DEC private modes (ESC[?nh), and resetPrivateMode undoes them
+// (ESC[?nl).  1 is application cursor keys, 7 is wrap and 25 shows the cursor.  Any we
+// don't know about are ignored.
+func (c *console) setPrivateMode(n int) {
+	switch n {
+	case 1:
+		c.setAppCursorKeys(true)
+	case 7:
+		c.wordWrap = true
+	case 25:
+		c.cursorVisible()
+	}
+}
+
+func (c *console) resetPrivateMode(n int) {
+	switch n {
+	case 1:
+		c.setAppCursorKeys(false)
+	case 7:
+		c.wordWrap = false
+	case 25:
+		c.cursorNotVisible()
+	}
+}
+
+// skipControlString throws away an OSC (ESC] ... BEL or ESC] ... ESC\), e.g. setting
+// the window title, which the console has no use for.
+func (c *console) skipControlString() {
+	for {
+		r, ok := c.stdoutBuffer.Dequeue()
+		if !ok || r == '\x07' {
+			return
+		}
+		if r == '\x1b' {
+			if next, ok := c.stdoutBuffer.Peek(); ok && next == '\\' {
+				c.stdoutBuffer.Dequeue()
+			}
+			return
+		}
+	}
+}
+
+// ansiEscape handles the VT100 escapes that don't start with a [.
+func (c *console) ansiEscape(r rune) {
+	switch r {
+	case '7':
+		c.saveCursorPosition()
+	case '8':
+		c.restoreCursorPosition()
+	case 'D':
+		// Index
+		c.lineFeed()
+	case 'E':
+		// Next line
+		c.lineFeed()
+		c.carriageReturn()
+	case 'M':
+		// Reverse index
+		if c.curpos[0] == 1 {
+			c.scrollDown(1)
+			return
+		}
+		c.curpos[0]--
+	case '=', '>':
+		// Keypad modes, which the keyboard doesn't have
+	}
+}
+
+// ansiControlChar handles the control chars that mean something in ANSI mode
+// but are printed as spaces in Nimbus mode.
+func (c *console) ansiControlChar(r rune) {
+	switch r {
+	case '\r':
+		c.carriageReturn()
+	case '\b':
+		c.ansiCursorMove(0, -1)
+	}
+}
+
+// ansiTab moves the cursor to the next tab stop, every 8 columns, without erasing
+// anything like the Nimbus tab does.
+func (c *console) ansiTab() {
+	_, w := c.getScrollingAreaSize()
+	col := (c.curpos[1]-1)/8*8 + 9
+	if col > w {
+		col = w
+	}
+	c.curpos[1] = col
+}
+
+// ansiBackTab moves the cursor back to the previous tab stop.
+func (c *console) ansiBackTab() {
+	col := (c.curpos[1]-2)/8*8 + 1
+	if col < 1 {
+		col = 1
+	}
+	c.curpos[1] = col
+}
+
+// repeatChar prints the last char printed n more times.
+func (c *console) repeatChar(n int) {
+	if c.lastChar == 0 {
+		return
+	}
+	for i := 0; i < ansiCount(n); i++ {
+		c.putChar(c.lastChar)
+	}
+}
+
+// ansiCursorMove moves the cursor rows down and cols right (or up and left if
+// they're negative), stopping at the edges of the scrolling area rather than
+// wrapping like the Nimbus cursor does.
+func (c *console) ansiCursorMove(rows, cols int) {
+	h, w := c.getScrollingAreaSize()
+	row, col := c.curpos[0]+rows, c.curpos[1]+cols
+	if row < 1 {
+		row = 1
+	}
+	if row > h {
+		row = h
+	}
+	if col < 1 {
+		col = 1
+	}
+	if col > w {
+		col = w
+	}
+	c.curpos = [2]int{row, col}
+}
+
+// ansiCount returns the count parameter of a cursor movement, where unset or 0 means 1.
+func ansiCount(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
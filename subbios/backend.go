@@ -0,0 +1,152 @@
+package subbios
+
+import (
+	"image"
+	"image/draw"
+	"sync"
+)
+
+// The size of the monitor image (the screen plus border) passed to Backend.Render.
+const (
+	MonitorWidth  = 640 + (50 * 2)
+	MonitorHeight = 500 + (50 * 2)
+)
+
+// Backend is where the video system sends the rendered monitor image.  Nimbus.Init
+// uses a backend that draws on an Ebiten image, while Subbios.Init uses a headless
+// ImageBackend so nothing needs a window or a GPU.
+type Backend interface {
+	// Render receives the rendered monitor image on each update.  The image is
+	// reused so it should be copied rather than kept.
+	Render(monitor *image.RGBA)
+}
+
+// Input is implemented by backends that can also supply keyboard and mouse input.
+// If the backend doesn't implement it then keyboard and mouse polling is skipped
+// and the draw queue is flushed whenever anything waits on it, since there's no
+// Ebiten game loop to do it.
+type Input interface {
+	// AppendInputChars appends the runes typed since the last update to runes.
+	AppendInputChars(runes []rune) []rune
+	// KeyPressDuration returns how many updates key has been held down for, or 0.
+	KeyPressDuration(key Key) int
+	// CursorPosition returns the mouse position in monitor image co-ordinates.
+	CursorPosition() (x, y int)
+	// IsMouseButtonPressed returns true if button is held down.
+	IsMouseButtonPressed(button MouseButton) bool
+}
+
+// Key identifies one of the keys the console and keyboard interrupts care about.
+type Key int
+
+// Keys known to Input.KeyPressDuration.
+const (
+	KeyEnter Key = iota
+	KeyBackspace
+	KeyLeft
+	KeyRight
+	KeyUp
+	KeyDown
+	KeyHome
+	KeyEnd
+	KeyTab
+	KeyF1
+	KeyF2
+	KeyF3
+	KeyF4
+	KeyF5
+	KeyF6
+	KeyF7
+	KeyF8
+	KeyF9
+	KeyF10
+	KeyF11
+	KeyF12
+	KeyControl
+	KeyShift
+	KeyC
+	KeyScrollLock
+	KeyEscape
+	KeyPageUp
+	KeyPageDown
+	KeyInsert
+	KeyDelete
+	KeyD
+	KeyAlt
+	KeyA
+	KeyB
+	KeyE
+	KeyF
+	KeyG
+	KeyH
+	KeyI
+	KeyJ
+	KeyK
+	KeyL
+	KeyM
+	KeyN
+	KeyO
+	KeyP
+	KeyQ
+	KeyR
+	KeyS
+	KeyT
+	KeyU
+	KeyV
+	KeyW
+	KeyX
+	KeyY
+	KeyZ
+	Key0
+	Key1
+	Key2
+	Key3
+	Key4
+	Key5
+	Key6
+	Key7
+	Key8
+	Key9
+	KeySpace
+	keyCount // How many keys there are
+)
+
+// MouseButton identifies a mouse button.
+type MouseButton int
+
+// Mouse buttons known to Input.IsMouseButtonPressed.
+const (
+	MouseButtonLeft MouseButton = iota
+	MouseButtonRight
+)
+
+// ImageBackend is a headless Backend that copies the monitor image into a
+// draw.Image, so Nimbus screens can be produced in batch jobs and tests.
+type ImageBackend struct {
+	mu  sync.Mutex
+	dst draw.Image
+}
+
+// NewImageBackend returns an ImageBackend that renders into dst.  If dst is nil
+// a new image.RGBA of MonitorWidth x MonitorHeight is allocated.
+func NewImageBackend(dst draw.Image) *ImageBackend {
+	if dst == nil {
+		dst = image.NewRGBA(image.Rect(0, 0, MonitorWidth, MonitorHeight))
+	}
+	return &ImageBackend{dst: dst}
+}
+
+// Render copies the monitor image into the destination image.
+func (b *ImageBackend) Render(monitor *image.RGBA) {
+	b.mu.Lock()
+	draw.Draw(b.dst, b.dst.Bounds(), monitor, image.Point{}, draw.Src)
+	b.mu.Unlock()
+}
+
+// Image returns the destination image.  Call Subbios.Update first to render the
+// latest state of the screen.
+func (b *ImageBackend) Image() draw.Image {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dst
+}
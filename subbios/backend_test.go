@@ -0,0 +1,62 @@
+package subbios
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/adamstimb/nimgobus/subbios/colour"
+)
+
+func TestImageBackend(t *testing.T) {
+	b := NewImageBackend(nil)
+	s := Subbios{}
+	s.InitWithBackend(b)
+	t.Cleanup(s.Close)
+	s.TGraphicsOutput.FSetBorderColour(4)
+	s.Update()
+
+	img := b.Image().(*image.RGBA)
+	if img.Bounds() != image.Rect(0, 0, MonitorWidth, MonitorHeight) {
+		t.Fatalf("ImageBackend image bounds are %v, expected %dx%d", img.Bounds(), MonitorWidth, MonitorHeight)
+	}
+	border := colour.PhysicalColours[colour.DefaultLowResColours[4].FirstPhysicalColour]
+	if c := img.RGBAAt(0, 0); c != border {
+		t.Errorf("border pixel is %v, expected %v", c, border)
+	}
+	screen := colour.PhysicalColours[0]
+	if c := img.RGBAAt(MonitorWidth/2, MonitorHeight/2); c != screen {
+		t.Errorf("screen pixel is %v, expected %v", c, screen)
+	}
+}
+
+func TestHoldDrawQueueHeadless(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	v := s.TGraphicsOutput.v
+	v.muHoldDrawQueue.Lock()
+	v.holdDrawQueue = true
+	v.muHoldDrawQueue.Unlock()
+	s.TGraphicsOutput.FPolyLine(1, []int{0}, 1, 0, 0, []int{10, 10, 10, 10})
+	s.Update()
+	if c := v.GetXY(10, 10); c != 0 {
+		t.Errorf("pixel drawn while the draw queue was held is %d, expected 0", c)
+	}
+	done := make(chan int)
+	go func() {
+		c, _ := s.TGraphicsOutput.ReadPixel(10, 10)
+		done <- c
+	}()
+	select {
+	case <-done:
+		t.Fatalf("ReadPixel didn't wait for the draw queue to be let go")
+	case <-time.After(50 * time.Millisecond):
+	}
+	v.muHoldDrawQueue.Lock()
+	v.holdDrawQueue = false
+	v.muHoldDrawQueue.Unlock()
+	if c := <-done; c != 1 {
+		t.Errorf("ReadPixel(10, 10) = %d after letting go of the draw queue, expected 1", c)
+	}
+}
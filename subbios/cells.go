@@ -0,0 +1,193 @@
+package subbios
+
+import (
+	"strings"
+
+	"github.com/adamstimb/nimgobus/internal/make2darray"
+)
+
+// Cell is what the console has put in one char cell of the screen.  Pen and Paper
+// are the logical colours the char was plotted in, i.e. after any bold, faint,
+// reverse or concealed attribute has done its thing.
+type Cell struct {
+	Char       int  // ASCII code of the char, 32 (space) if the cell is empty
+	CharSet    int  // 0 standard, 1 alternative
+	Pen        int  // Logical colour of the char
+	Paper      int  // Logical colour of the background
+	Underlined bool // Set to true if the char is underlined
+	XOR        bool // Set to true if the char was XORed onto the screen
+	Bold       bool // Set to true if the char was printed with the bold attribute
+	Faint      bool // Set to true if the char was printed with the faint attribute
+	Reverse    bool // Set to true if the char was printed in reverse video
+	Concealed  bool // Set to true if the char was printed concealed
+}
+
+// The size of the cell grid, which is big enough for 80 column mode.
+const (
+	cellRows = 25
+	cellCols = 80
+)
+
+// GetCell returns what the console has put in the char cell at row, col of the
+// screen (not the scrolling area), counting from 1, 1 at the top-left.  Only the
+// console is tracked, so anything drawn over the text by the graphics functions
+// isn't.  Outside the screen you get an empty Cell.
+func (sio *Stdio) GetCell(row, col int) Cell {
+	c := sio.c
+	c.muCells.Lock()
+	defer c.muCells.Unlock()
+	if row < 1 || row > cellRows || col < 1 || col > c.v.screenWidth {
+		return Cell{}
+	}
+	return c.cells[row-1][col-1]
+}
+
+// GetScreenText returns the text on the screen, one string per row with trailing
+// spaces trimmed.  Chars are converted to runes as they are, so the Nimbus
+// specials above 127 and anything in the alternative charset won't look quite
+// right, and control chars become spaces.
+func (sio *Stdio) GetScreenText() []string {
+	c := sio.c
+	c.muCells.Lock()
+	defer c.muCells.Unlock()
+	rows := make([]string, cellRows)
+	for r := range rows {
+		var b strings.Builder
+		for col := 0; col < c.v.screenWidth; col++ {
+			ch := c.cells[r][col].Char
+			if ch < 32 {
+				ch = ' '
+			}
+			b.WriteRune(rune(ch))
+		}
+		rows[r] = strings.TrimRight(b.String(), " ")
+	}
+	return rows
+}
+
+// blankCell returns an empty cell in the current paper colour.
+func (c *console) blankCell() Cell {
+	return Cell{Char: ' ', Pen: c.penColour, Paper: c.paperColour}
+}
+
+// setCell records a char plotted at row, col of the scrolling area.  Anything off
+// the grid is ignored.
+func (c *console) setCell(row, col int, cell Cell) {
+	c.muCells.Lock()
+	defer c.muCells.Unlock()
+	r, cl := c.absoluteCell(row, col)
+	if !onGrid(r, cl) {
+		return
+	}
+	c.cells[r][cl] = cell
+}
+
+// onGrid returns true if r, cl are indices into the grid.
+func onGrid(r, cl int) bool {
+	return r >= 0 && r < cellRows && cl >= 0 && cl < cellCols
+}
+
+// clearCells empties the whole grid, e.g. after the screen is cleared.
+func (c *console) clearCells() {
+	c.muCells.Lock()
+	defer c.muCells.Unlock()
+	for r := range c.cells {
+		for col := range c.cells[r] {
+			c.cells[r][col] = Cell{Char: ' ', Pen: c.penColour, Paper: c.paperColour}
+		}
+	}
+}
+
+// absoluteCell converts row, col of the scrolling area to indices into the grid.
+func (c *console) absoluteCell(row, col int) (r, cl int) {
+	return row + c.scrollingArea[0] - 2, col + c.scrollingArea[1] - 2
+}
+
+// eraseArea fills rows r1 to r2 and columns c1 to c2 of the scrolling area with
+// paper and empties their cells.
+func (c *console) eraseArea(r1, c1, r2, c2 int) {
+	if r1 > r2 || c1 > c2 {
+		return
+	}
+	x1, y1 := c.convertAnyCurposToXY(r1, c1)
+	x2, y2 := c.convertAnyCurposToXY(r2, c2)
+	paperImg := make2darray.Make2dArray((x2+8)-x1, (y1+10)-y2, c.paperColour)
+	c.v.drawFeature(feature{pixels: paperImg, x: x1, y: y2, colour: -1, xor: false})
+	blank := c.blankCell()
+	c.muCells.Lock()
+	defer c.muCells.Unlock()
+	for row := r1; row <= r2; row++ {
+		for col := c1; col <= c2; col++ {
+			if r, cl := c.absoluteCell(row, col); onGrid(r, cl) {
+				c.cells[r][cl] = blank
+			}
+		}
+	}
+}
+
+// shiftCells moves the cells of rows r1 to r2 and columns c1 to c2 of the scrolling
+// area up by rows and left by cols (down or right if they're negative), like
+// shiftRows and shiftColumns do to the pixels.  Cells that come from outside the
+// block are left alone, since they'll have been erased.
+func (c *console) shiftCells(r1, c1, r2, c2, rows, cols int) {
+	c.muCells.Lock()
+	defer c.muCells.Unlock()
+	old := c.cells
+	for row := r1; row <= r2; row++ {
+		for col := c1; col <= c2; col++ {
+			srcRow, srcCol := row+rows, col+cols
+			if srcRow < r1 || srcRow > r2 || srcCol < c1 || srcCol > c2 {
+				continue
+			}
+			r, cl := c.absoluteCell(row, col)
+			sr, scl := c.absoluteCell(srcRow, srcCol)
+			if onGrid(r, cl) && onGrid(sr, scl) {
+				c.cells[r][cl] = old[sr][scl]
+			}
+		}
+	}
+}
+
+// plonk plots r at the cursor and records it in the cell grid, without moving the
+// cursor.
+func (c *console) plonk(r rune) {
+	x, y := c.convertCurposToXY()
+	c.v.plonkChar(int(r), x, y, c.penColour, c.paperColour, c.charSet, c.xorWriting, c.underlined, c.attributes)
+	fg, bg := c.attributes.colours(c.penColour, c.paperColour, c.v.screenWidth)
+	c.setCell(c.curpos[0], c.curpos[1], Cell{
+		Char:       int(r),
+		CharSet:    c.charSet,
+		Pen:        fg,
+		Paper:      bg,
+		Underlined: c.underlined,
+		XOR:        c.xorWriting,
+		Bold:       c.attributes.bold,
+		Faint:      c.attributes.faint,
+		Reverse:    c.attributes.reverse,
+		Concealed:  c.attributes.concealed,
+	})
+}
+
+// getCell returns the cell at row, col of the scrolling area, or an empty Cell if
+// it's off the grid.
+func (c *console) getCell(row, col int) Cell {
+	c.muCells.Lock()
+	defer c.muCells.Unlock()
+	r, cl := c.absoluteCell(row, col)
+	if !onGrid(r, cl) {
+		return Cell{}
+	}
+	return c.cells[r][cl]
+}
+
+// drawCell plots cell at row, col of the scrolling area as it was and records it.
+// What was under a char that was XORed onto the screen isn't known, so it's XORed
+// onto a blank cell of its paper colour.
+func (c *console) drawCell(row, col int, cell Cell) {
+	x, y := c.convertAnyCurposToXY(row, col)
+	if cell.XOR {
+		c.v.plonkChar(' ', x, y, cell.Paper, cell.Paper, 0, false, false, charAttributes{})
+	}
+	c.v.plonkChar(cell.Char, x, y, cell.Pen, cell.Paper, cell.CharSet, cell.XOR, cell.Underlined, charAttributes{})
+	c.setCell(row, col, cell)
+}
@@ -0,0 +1,37 @@
+package subbios
+
+import "testing"
+
+func TestCellsOffGrid(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	c := s.Stdio.c
+	// None of these should panic
+	if cell := c.getCell(0, 1); cell != (Cell{}) {
+		t.Errorf("getCell(0, 1) = %+v, want an empty Cell", cell)
+	}
+	if cell := c.getCell(1, 81); cell != (Cell{}) {
+		t.Errorf("getCell(1, 81) = %+v, want an empty Cell", cell)
+	}
+	c.eraseArea(24, 1, 27, 80)
+	c.shiftCells(24, 1, 27, 80, 1, 0)
+
+	// A char that was XORed is XORed again onto its paper when it's put back
+	cell := Cell{Char: 'A', Pen: 3, Paper: 1, XOR: true}
+	c.drawCell(1, 1, cell)
+	c.drawCell(1, 1, cell)
+	mem := s.VideoMemory()
+	seen := map[int]bool{}
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 8; x++ {
+			seen[mem[y][x]] = true
+		}
+	}
+	if len(seen) != 2 || !seen[0] || !seen[3^1] {
+		t.Errorf("XORed cell has colours %v, want 0 and %d", seen, 3^1)
+	}
+	if got := c.getCell(1, 1); got != cell {
+		t.Errorf("getCell(1, 1) = %+v, want %+v", got, cell)
+	}
+}
@@ -10,6 +10,33 @@ type CltElement struct {
 	SecondPhysicalColour int
 }
 
+// The physical colours, which index PhysicalColours
+const (
+	Black = iota
+	DarkBlue
+	DarkRed
+	Purple
+	DarkGreen
+	DarkCyan
+	Brown
+	LightGrey
+	DarkGrey
+	LightBlue
+	LightRed
+	LightPurple
+	LightGreen
+	LightCyan
+	Yellow
+	White
+)
+
+// The flash speeds of a CltElement
+const (
+	NoFlash = iota
+	SlowFlash
+	FastFlash
+)
+
 var (
 	PhysicalColours = []color.RGBA{
 		{0x00, 0x00, 0x00, 0xff}, // black
@@ -1,27 +1,42 @@
 package subbios
 
 import (
+	"io"
 	"strconv"
 	"strings"
+	"sync"
 	"unicode"
 
 	"github.com/adamstimb/nimgobus/internal/make2darray"
 	"github.com/adamstimb/nimgobus/internal/queue"
-	"github.com/adamstimb/nimgobus/internal/subbios/colour"
-	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/adamstimb/nimgobus/subbios/colour"
 )
 
 // console holds all the console io malarky.
 type console struct {
-	curpos                  [2]int             // Cursor position {row, col}
-	savedCurpos             [2]int             // SCP stores the current curpos here, RCP and CPR pull the value from here
-	penColour               int                // Pen colour
-	paperColour             int                // Paper colour
-	charSet                 int                // Selected charset
-	wordWrap                bool               // Set to true is word wrap is on (Default)
-	underlined              bool               // Set to true for underlined chars
-	xorWriting              bool               // Set to true for XOR writing
-	printControlChars       bool               // Set to true to enable printing of control chars
+	curpos                  [2]int         // Cursor position {row, col}
+	savedCurpos             [2]int         // SCP stores the current curpos here, RCP and CPR pull the value from here
+	penColour               int            // Pen colour
+	paperColour             int            // Paper colour
+	charSet                 int            // Selected charset
+	wordWrap                bool           // Set to true is word wrap is on (Default)
+	underlined              bool           // Set to true for underlined chars
+	attributes              charAttributes // Bold, faint, reverse and concealed
+	attributesOn            bool           // Set to true to act on SGR 1, 2, 7 and 8 in Nimbus mode
+	xorWriting              bool           // Set to true for XOR writing
+	printControlChars       bool           // Set to true to enable printing of control chars
+	ansiMode                bool           // Set to true to use standard ANSI escape sequences instead of Nimbus ones
+	appCursorKeys           bool           // Set to true when the terminal should send cursor keys as ESC O A etc.
+	muTerminal              sync.Mutex
+	terminal                io.Writer // Where keys go instead of the stdin buffer while RunTerminal is running
+	lastChar                rune      // The last char printed, for REP
+	muCells                 sync.Mutex
+	cells                   [cellRows][cellCols]Cell // What's in each char cell of the screen
+	muScrollback            sync.Mutex
+	scrollback              [][]Cell           // Lines that have scrolled off the top of the scrolling area, oldest first
+	scrollbackSize          int                // The most lines the scrollback keeps
+	scrollbackOffset        int                // How many lines up the scrollback the view is, 0 for the live screen
+	bell                    func()             // Rings the bell
 	cursorUnderlined        bool               // Set to true for underlined cursor
 	cursorCharSet           int                // Charset for the cursor
 	cursorFlashing          bool               // Set to true for flashing cursor
@@ -37,38 +52,22 @@ type console struct {
 }
 
 // Update should be called on each Ebiten Update call
-func (c *console) update() {
-	// Transfer runs from Ebiten buffer to stdinBuffer - this is mainly to support the Stdio.Scanf() feature.
-	newRunes := make([]rune, 1)
-	ebiten.AppendInputChars(newRunes[:0])
-	// Detect any other keys that the TextBox needs to know about
-	if repeatingKeyPressed(ebiten.KeyEnter) || repeatingKeyPressed(ebiten.KeyNumpadEnter) {
-		c.stdinBuffer.Enqueue('\n')
-		return
-	}
-	if repeatingKeyPressed(ebiten.KeyBackspace) {
-		c.stdinBuffer.Enqueue('\x08')
+func (c *console) update(in Input) {
+	// Browsing the scrollback doesn't bother the program
+	if c.scrollbackKeys(in) {
 		return
 	}
-	// Left, Right, Up, Down are encoded as x01, x02, x03, x04 respectively.
-	if repeatingKeyPressed(ebiten.KeyLeft) {
-		c.stdinBuffer.Enqueue('\x01')
+	// Keys go to the terminal instead if there is one
+	if c.sendToTerminal(in) {
 		return
 	}
-	if repeatingKeyPressed(ebiten.KeyRight) {
-		c.stdinBuffer.Enqueue('\x02')
-		return
-	}
-	// Keys that were considered a "hit" by Nimbus but don't have any effect on Scanf.  They are queued as \x05.  This
-	// is useful for Getchar if applied to a "Press any key..." scenario.
-	if repeatingKeyPressed(ebiten.KeyF1) || repeatingKeyPressed(ebiten.KeyF2) || repeatingKeyPressed(ebiten.KeyF3) || repeatingKeyPressed(ebiten.KeyF4) ||
-		repeatingKeyPressed(ebiten.KeyF5) || repeatingKeyPressed(ebiten.KeyF6) || repeatingKeyPressed(ebiten.KeyF7) || repeatingKeyPressed(ebiten.KeyF8) ||
-		repeatingKeyPressed(ebiten.KeyF9) || repeatingKeyPressed(ebiten.KeyF10) || repeatingKeyPressed(ebiten.KeyF11) || repeatingKeyPressed(ebiten.KeyF12) ||
-		repeatingKeyPressed(ebiten.KeyTab) || repeatingKeyPressed(ebiten.KeyUp) || repeatingKeyPressed(ebiten.KeyDown) || repeatingKeyPressed(ebiten.KeyHome) ||
-		repeatingKeyPressed(ebiten.KeyEnd) {
-		c.stdinBuffer.Enqueue('\x07')
-		return
+	// Keys that aren't chars are encoded as key codes, see keys.go, and go before
+	// the chars typed in the same frame
+	for _, r := range keyCodes(in) {
+		c.stdinBuffer.Enqueue(r)
 	}
+	// Transfer runs from Ebiten buffer to stdinBuffer - this is mainly to support the Stdio.Scanf() feature.
+	newRunes := in.AppendInputChars(nil)
 	// Detect printable chars
 	for _, r := range newRunes {
 		if r != 0 {
@@ -77,6 +76,38 @@ func (c *console) update() {
 	}
 }
 
+// charAttributes are the optional SGR attributes that change the colours a char is
+// plotted in.
+type charAttributes struct {
+	bold      bool // Pen uses the light colour of its pair
+	faint     bool // Pen uses the dark colour of its pair
+	reverse   bool // Pen and paper are swapped
+	concealed bool // Pen is drawn in the paper colour
+}
+
+// colours returns the pen and paper colours to plot a char in with these attributes.
+// In 40 column mode the light/dark pairs are 0-7 and 8-15, and in 80 column mode
+// they're 1 (light grey) and 3 (white).
+func (a charAttributes) colours(fg, bg, screenWidth int) (int, int) {
+	switch {
+	case a.bold && screenWidth == 40 && fg < 8:
+		fg += 8
+	case a.bold && screenWidth == 80 && fg == 1:
+		fg = 3
+	case a.faint && screenWidth == 40 && fg >= 8:
+		fg -= 8
+	case a.faint && screenWidth == 80 && fg == 3:
+		fg = 1
+	}
+	if a.reverse {
+		fg, bg = bg, fg
+	}
+	if a.concealed {
+		fg = bg
+	}
+	return fg, bg
+}
+
 // getScrollingAreaSize returns the height and width of the scrolling area
 func (c *console) getScrollingAreaSize() (height, width int) {
 	height = c.scrollingArea[2] - (c.scrollingArea[0] - 1)
@@ -166,6 +197,7 @@ func (c *console) tab() {
 	for c.curpos[1] != nextCol {
 		x, y := c.convertCurposToXY()
 		c.v.drawFeature(feature{pixels: delImg, x: x, y: y, colour: -1, xor: c.xorWriting})
+		c.setCell(c.curpos[0], c.curpos[1], c.blankCell())
 		c.cursorForward(1)
 	}
 
@@ -202,26 +234,84 @@ func (c *console) executeEscapeSequence(params []int, esType string) {
 	case "_c":
 		c.resetToInitialState()
 	case "_C":
+		if c.ansiMode {
+			c.ansiCursorMove(0, ansiCount(params[0]))
+			return
+		}
 		c.cursorForward(params[0])
 	case "_D":
+		if c.ansiMode {
+			c.ansiCursorMove(0, -ansiCount(params[0]))
+			return
+		}
 		c.cursorBackward(params[0])
 	case "_S":
 		c.scrollUp(params[0])
 	case "_T":
 		c.scrollDown(params[0])
 	case "_A":
+		if c.ansiMode {
+			c.ansiCursorMove(-ansiCount(params[0]), 0)
+			return
+		}
 		c.cursorUp(params[0])
 	case "_B":
+		if c.ansiMode {
+			c.ansiCursorMove(ansiCount(params[0]), 0)
+			return
+		}
 		c.cursorDown(params[0])
+	case "_G":
+		c.cursorHorizontalAbsolute(params[0])
+	case "_d":
+		c.linePositionAbsolute(params[0])
+	case "_f":
+		c.cursorPosition(params[0], params[1])
+	case "_X":
+		c.eraseCharacter(params[0])
+	case "_b":
+		c.repeatChar(params[0])
+	case "_I":
+		for i := 0; i < ansiCount(params[0]); i++ {
+			c.ansiTab()
+		}
+	case "_Z":
+		for i := 0; i < ansiCount(params[0]); i++ {
+			c.ansiBackTab()
+		}
+	case "_L":
+		c.insertLine(params[0])
+	case "_M":
+		c.deleteLine(params[0])
+	case "_@":
+		c.insertChar(params[0])
+	case "_P":
+		c.deleteChar(params[0])
 	case "_J":
 		c.eraseInDisplay(params[0])
 	case "_K":
 		c.eraseInLine(params[0])
 	case "~D":
 		c.enablePrintControlChars(params[0])
+	case "_n":
+		c.deviceStatusReport(params[0])
+	case "~H":
+		c.screenStatusReport()
+	case "~I":
+		c.setANSIMode(params[0])
+	case "?h":
+		c.setPrivateMode(params[0])
+	case "?l":
+		c.resetPrivateMode(params[0])
 	}
 }
 
+// isFinalRune returns true if r ends an escape sequence.  That's usually a letter
+// but ICH ends with @.
+func isFinalRune(r rune) bool {
+	return unicode.IsLetter(r) || r == '@'
+}
+
 // parseEscapeSequence continues to flush the buffer but interprets it
 // as an escape sequence
 func (c *console) parseEscapeSequence() bool {
@@ -232,6 +322,27 @@ func (c *console) parseEscapeSequence() bool {
 	params = []int{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1}
 	paramIndex := 0
 	esType := make([]rune, 2) // the escape code type is then stored her (the 1st rune is reserved for ~)
+	// OSCs and charset designations aren't CSIs so deal with them first
+	if r, ok := c.stdoutBuffer.Peek(); ok {
+		switch r {
+		case ']':
+			c.stdoutBuffer.Dequeue()
+			c.skipControlString()
+			return true
+		case '7', '8', 'D', 'E', 'M', '=', '>':
+			// VT100 escapes without a [, which only make sense in ANSI mode
+			if c.ansiMode {
+				c.stdoutBuffer.Dequeue()
+				c.ansiEscape(r)
+				return true
+			}
+		case '(', ')', '*', '+':
+			// Designating a charset, e.g. ESC(B - ignore it and the charset
+			c.stdoutBuffer.Dequeue()
+			c.stdoutBuffer.Dequeue()
+			return true
+		}
+	}
 	// Valid, so go ahead
 	for c.stdoutBuffer.Size() > 0 {
 		if r, ok := c.stdoutBuffer.Dequeue(); ok {
@@ -247,7 +358,7 @@ func (c *console) parseEscapeSequence() bool {
 			}
 			if unicode.IsDigit(r) {
 				// collecting value
-				if valueIndex > 3 {
+				if valueIndex >= len(value) {
 					// value is too big therefore invalid sequence
 					return false
 				}
@@ -255,12 +366,16 @@ func (c *console) parseEscapeSequence() bool {
 				valueIndex++
 				continue
 			}
-			if unicode.IsLetter(r) || r == ';' {
+			if isFinalRune(r) || r == ';' {
 				// convert and store current value and move to next
 				// catch empty/unset value
 				if string(value) == "   " {
 					value = []rune{'-', '1'}
 				}
+				if paramIndex >= len(params) {
+					// too many params therefore invalid
+					return false
+				}
 				p, err := strconv.Atoi(strings.TrimSpace(string(value)))
 				if err != nil {
 					panic(err)
@@ -269,21 +384,17 @@ func (c *console) parseEscapeSequence() bool {
 				valueIndex = 0
 				paramIndex++
 				value = []rune{' ', ' ', ' '}
-				if paramIndex > 31 {
-					// too many params therefore invalid
-					return false
-				}
 				// skip if ;, otherwise continue to parse the rune
 				if r == ';' {
 					continue
 				}
 			}
-			if r == '~' {
-				// collect ~ prefix for esType
+			if r == '~' || r == '?' || r == '>' || r == '=' {
+				// collect ~ prefix (or ANSI private-mode prefix) for esType
 				esType[0] = r
 				continue
 			}
-			if unicode.IsLetter(r) {
+			if isFinalRune(r) {
 				// get esType and execute
 				esType[1] = r
 				// tidy-up esType
@@ -291,6 +402,10 @@ func (c *console) parseEscapeSequence() bool {
 					esType[0] = '_' // otherwise evaluator gets into trouble doing the string matching
 				}
 				c.executeEscapeSequence(params, string(esType))
+				// In ANSI mode every sequence starts with its own ESC
+				if c.ansiMode {
+					return true
+				}
 				// Is there another sequence following? Return if not.
 				if val, ok := c.stdoutBuffer.Peek(); ok {
 					if val != '[' {
@@ -325,26 +440,54 @@ func (c *console) flushStdoutBuffer() {
 				c.parseEscapeSequence()
 				continue
 			case '\t':
+				if c.ansiMode {
+					c.ansiTab()
+					continue
+				}
 				c.tab()
 				continue
+			case '\r', '\b':
+				if c.ansiMode {
+					c.ansiControlChar(r)
+					continue
+				}
 			case '\n':
 				c.lineFeed()
 				c.carriageReturn()
 				continue
+			case '\x07':
+				// BEL
+				if !c.printControlChars {
+					c.bell()
+					continue
+				}
 			}
 			// Control char
 			if r < 32 && !c.printControlChars {
+				if c.ansiMode {
+					// Anything else like SI and NUL is just ignored
+					continue
+				}
 				r = 32
 			}
 			// Otherwise plonk the char
-			x, y := c.convertCurposToXY()
-			oldRow := c.curpos[0]
-			c.v.plonkChar(int(r), x, y, c.penColour, c.paperColour, c.charSet, c.xorWriting, c.underlined)
-			c.cursorForward(1)
-			if c.curpos[0] == oldRow && c.curpos[1] == 1 {
-				// scroll up required
-				c.scrollUp(1)
-			}
+			c.putChar(r)
 		}
 	}
 }
+
+// putChar plonks a char at the cursor and moves the cursor on, scrolling up if need be.
+func (c *console) putChar(r rune) {
+	// There are only 256 chars in a charset
+	if r > 255 {
+		r = '?'
+	}
+	c.lastChar = r
+	oldRow := c.curpos[0]
+	c.plonk(r)
+	c.cursorForward(1)
+	if c.curpos[0] == oldRow && c.curpos[1] == 1 {
+		// scroll up required
+		c.scrollUp(1)
+	}
+}
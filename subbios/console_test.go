@@ -0,0 +1,66 @@
+package subbios_test
+
+import (
+	"testing"
+
+	"github.com/adamstimb/nimgobus/subbios/subbiostest"
+)
+
+func TestStatusReports(t *testing.T) {
+	s := subbiostest.New(t)
+	s.Stdio.KeyboardBufferFlush()
+	read := func() string {
+		st := ""
+		for r := s.Stdio.Getch(); r != 0; r = s.Stdio.Getch() {
+			st += string(r)
+		}
+		return st
+	}
+	tests := []struct {
+		printed string
+		want    string
+	}{
+		{"\x1b[5n", "\x1b[0n"},
+		{"\x1b[5;10H\x1b[6n", "\x1b[5;10R"},
+		{"\x1b[3;5;20;60~B\x1b[2;3H\x1b[6n", "\x1b[2;3R"}, // Relative to the scrolling area
+		{"\x1b[~H", "\x1b[2;3;5;20;60~H"},
+		{"\x1b[0h\x1b[~H", "\x1b[0;1;1;25;40~H"},
+	}
+	for _, test := range tests {
+		s.Stdio.Printf(test.printed)
+		if got := read(); got != test.want {
+			t.Errorf("%q replied %q, want %q", test.printed, got, test.want)
+		}
+	}
+}
+
+func TestCells(t *testing.T) {
+	s := subbiostest.New(t)
+	s.Stdio.Printf("\x1b[2J\x1b[1;1Hhello\x1b[1;1H\x1b[2@\x1b[2;1Hworld\x1b[1;4H\x1b[1K")
+	s.Stdio.SetAttributes(true)
+	s.Stdio.Printf("\x1b[3;1H\x1b[7mX\x1b[27m\x1b[~I\x1b[3;2H\x1b[1;34mY\x1b[0m\x1b[0~I")
+	s.Stdio.SetAttributes(false)
+	s.Stdio.Printf("\x1b[4;1H\x1b[7mZ\x1b[0m")
+	got := s.Stdio.GetScreenText()
+	want := []string{"    llo", "world", "XY", "Z"}
+	for i, w := range want {
+		if got[i] != w {
+			t.Errorf("row %d is %q, want %q", i+1, got[i], w)
+		}
+	}
+	if len(got) != 25 || got[4] != "" {
+		t.Errorf("got %d rows with row 5 %q, want 25 with row 5 empty", len(got), got[4])
+	}
+	if cell := s.Stdio.GetCell(3, 1); !cell.Reverse || cell.Char != 'X' {
+		t.Errorf("GetCell(3, 1) = %+v, want a reversed X", cell)
+	}
+	if cell := s.Stdio.GetCell(3, 2); !cell.Bold || cell.Reverse || cell.Char != 'Y' {
+		t.Errorf("GetCell(3, 2) = %+v, want a bold Y", cell)
+	}
+	if cell := s.Stdio.GetCell(4, 1); cell.Reverse {
+		t.Errorf("GetCell(4, 1) = %+v, want Z not reversed with the attributes off", cell)
+	}
+	if cell := s.Stdio.GetCell(0, 1); cell != (s.Stdio.GetCell(26, 1)) || cell.Char != 0 {
+		t.Errorf("GetCell off the screen = %+v, want an empty Cell", cell)
+	}
+}
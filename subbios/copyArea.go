@@ -0,0 +1,55 @@
+package subbios
+
+// areaCopy describes a block of video memory to be copied somewhere else.  It goes
+// through the draw queue as part of a feature so it happens in the right order with
+// everything else being drawn.
+type areaCopy struct {
+	xMin, yMin, xMax, yMax int  // The source area
+	xDest, yDest           int  // Where the bottom-left of the source area goes
+	xor                    bool // XOR the source onto the destination
+	ignoreLogicalColour    int  // Pixels of this colour aren't copied, or -1 to copy everything
+}
+
+// copyArea copies a block of video memory within itself.  Only destination pixels
+// inside clip are written.  Rows and columns are copied in whichever direction means
+// source pixels are always read before they get overwritten, so it's fine for the
+// source and destination to overlap.  It assumes video memory is locked.
+func (v *video) copyArea(c *areaCopy, clip clippingArea) {
+	dx := c.xDest - c.xMin
+	dy := c.yDest - c.yMin
+	// Work in screen co-ordinates (y up) and pick the directions
+	yFrom, yTo, yStep := c.yMin, c.yMax, 1
+	if dy > 0 {
+		yFrom, yTo, yStep = c.yMax, c.yMin, -1
+	}
+	xFrom, xTo, xStep := c.xMin, c.xMax, 1
+	if dx > 0 {
+		xFrom, xTo, xStep = c.xMax, c.xMin, -1
+	}
+	for y := yFrom; ; y += yStep {
+		destY := y + dy
+		if destY >= clip.MinY && destY <= clip.MaxY && destY >= 0 && destY <= 249 {
+			src := &v.memory[249-y]
+			dst := &v.memory[249-destY]
+			for x := xFrom; ; x += xStep {
+				destX := x + dx
+				if destX >= clip.MinX && destX <= clip.MaxX && destX >= 0 && destX <= 639 {
+					p := src[x]
+					if p != c.ignoreLogicalColour {
+						if c.xor {
+							dst[destX] ^= p
+						} else {
+							dst[destX] = p
+						}
+					}
+				}
+				if x == xTo {
+					break
+				}
+			}
+		}
+		if y == yTo {
+			break
+		}
+	}
+}
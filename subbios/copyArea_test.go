@@ -0,0 +1,66 @@
+package subbios
+
+import "testing"
+
+func TestCopyAreaPixel(t *testing.T) {
+	tests := []struct {
+		name                   string
+		xMin, yMin, xMax, yMax int
+		xDest, yDest           int
+		xor                    bool
+		ignore                 int
+		clip                   clippingArea
+	}{
+		{"apart", 10, 10, 50, 40, 200, 100, false, -1, clippingArea{0, 0, 639, 249}},
+		{"overlap up right", 10, 10, 100, 80, 15, 13, false, -1, clippingArea{0, 0, 639, 249}},
+		{"overlap down left", 20, 20, 100, 80, 15, 17, false, -1, clippingArea{0, 0, 639, 249}},
+		{"overlap up left", 20, 20, 100, 80, 15, 23, false, -1, clippingArea{0, 0, 639, 249}},
+		{"same row", 20, 20, 100, 80, 25, 20, false, -1, clippingArea{0, 0, 639, 249}},
+		{"xor", 10, 10, 100, 80, 12, 11, true, -1, clippingArea{0, 0, 639, 249}},
+		{"ignore", 10, 10, 100, 80, 12, 11, false, 2, clippingArea{0, 0, 639, 249}},
+		{"clipped", 10, 10, 100, 80, 600, 200, false, -1, clippingArea{30, 30, 620, 220}},
+	}
+	for _, tt := range tests {
+		s := Subbios{}
+		s.Init()
+		t.Cleanup(s.Close)
+		s.Stdio.Printf("\x1b~F") // Hide cursor
+		img := [][]int{}
+		for y := 0; y < 250; y++ {
+			row := []int{}
+			for x := 0; x < 640; x++ {
+				row = append(row, (x*7+y*3+x*y)%4)
+			}
+			img = append(img, row)
+		}
+		s.TGraphicsOutput.FWriteAreaPixel(img, 0, 0, false, -1)
+		s.TGraphicsOutput.FSetOutputClippingAreaLimits(1, tt.clip.MinX, tt.clip.MinY, tt.clip.MaxX, tt.clip.MaxY)
+		s.TGraphicsOutput.FSetCurrentOutputClippingArea(1)
+		// Work out what should happen the slow way
+		want := s.VideoMemory()
+		before := want
+		for y := tt.yMin; y <= tt.yMax; y++ {
+			for x := tt.xMin; x <= tt.xMax; x++ {
+				dx, dy := x+tt.xDest-tt.xMin, y+tt.yDest-tt.yMin
+				if dx < tt.clip.MinX || dx > tt.clip.MaxX || dy < tt.clip.MinY || dy > tt.clip.MaxY {
+					continue
+				}
+				p := before[249-y][x]
+				if p == tt.ignore {
+					continue
+				}
+				if tt.xor {
+					want[249-dy][dx] ^= p
+				} else {
+					want[249-dy][dx] = p
+				}
+			}
+		}
+		if err := s.TGraphicsOutput.CopyAreaPixel(tt.xMin, tt.yMin, tt.xMax, tt.yMax, tt.xDest, tt.yDest, tt.xor, tt.ignore); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if got := s.VideoMemory(); got != want {
+			t.Errorf("%s: video memory is wrong", tt.name)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package subbios
+
+import "math"
+
+// ellipseParam converts an angle in thousandths of a radian (0 is up, clockwise) to
+// the parametric angle of the point on an ellipse in that direction, in radians.
+func ellipseParam(theta, xRadius, yRadius int) float64 {
+	phi := float64(theta) / 1000.0
+	t := math.Atan2(float64(yRadius)*math.Sin(phi), float64(xRadius)*math.Cos(phi))
+	if t < 0 {
+		t += 2 * math.Pi
+	}
+	return t
+}
+
+// ellipsePoints returns the pixels along an arc of an ellipse, in order, going
+// clockwise from theta1 to theta2.  The angles are in thousandths of a radian with 0
+// being vertically up, like FPieSlice, and if theta1 == theta2 the whole ellipse is
+// returned.
+func ellipsePoints(xCentre, yCentre, xRadius, yRadius, theta1, theta2 int) []coord {
+	return ellipseArc(xCentre, yCentre, xRadius, yRadius, ellipseParam(theta1, xRadius, yRadius), ellipseParam(theta2, xRadius, yRadius), theta1 == theta2)
+}
+
+// ellipseArc is ellipsePoints with parametric angles in radians, which is what you
+// want for a circle that's been stretched into an ellipse.  The arc is sampled finely
+// enough that neighbouring pixels always touch, then any pixels that make a corner
+// rather than a curve are dropped so the line is one pixel thin.
+func ellipseArc(xCentre, yCentre, xRadius, yRadius int, t1, t2 float64, whole bool) []coord {
+	sweep := t2 - t1
+	if whole {
+		sweep = 2 * math.Pi
+	} else if sweep <= 0 {
+		sweep += 2 * math.Pi
+	}
+	maxRadius := xRadius
+	if yRadius > maxRadius {
+		maxRadius = yRadius
+	}
+	n := int(math.Ceil(sweep*float64(maxRadius)*2)) + 1
+	points := []coord{}
+	for i := 0; i <= n; i++ {
+		t := t1 + sweep*float64(i)/float64(n)
+		p := coord{
+			X: xCentre + int(math.Round(float64(xRadius)*math.Sin(t))),
+			Y: yCentre + int(math.Round(float64(yRadius)*math.Cos(t))),
+		}
+		if len(points) > 0 && points[len(points)-1] == p {
+			continue
+		}
+		points = append(points, p)
+	}
+	// A whole ellipse ends where it started
+	if whole && len(points) > 1 && points[len(points)-1] == points[0] {
+		points = points[:len(points)-1]
+	}
+	// Drop corners
+	thin := []coord{}
+	for i, p := range points {
+		if i > 0 && i < len(points)-1 && len(thin) > 0 {
+			prev, next := thin[len(thin)-1], points[i+1]
+			if abs(next.X-prev.X) <= 1 && abs(next.Y-prev.Y) <= 1 {
+				continue
+			}
+		}
+		thin = append(thin, p)
+	}
+	return thin
+}
+
+// drawPoints draws a series of connected pixels on a 2d array, carrying the line
+// style along the whole lot rather than restarting it for each pixel like drawLine
+// would.
+func (v *video) drawPoints(img [][]int, points []coord, lineStyle int, lineStyleIndex []int, firstLogicalColour, secondLogicalColour, transparency int) [][]int {
+	imgHeight := len(img) - 1
+	v.lineStyleCounter = 0
+	for _, p := range points {
+		img[imgHeight-p.Y][p.X] = v.lineColour(p.X, imgHeight-p.Y, lineStyle, lineStyleIndex, firstLogicalColour, secondLogicalColour, transparency)
+		v.advanceLineStyleCounter()
+	}
+	return img
+}
+
+// linePoints returns the pixels along a straight line from (x1, y1) to (x2, y2), in
+// order, using Bresenham's algorithm.
+func linePoints(x1, y1, x2, y2 int) []coord {
+	points := []coord{}
+	dx, dy := abs(x2-x1), -abs(y2-y1)
+	sx, sy := 1, 1
+	if x1 > x2 {
+		sx = -1
+	}
+	if y1 > y2 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		points = append(points, coord{X: x1, Y: y1})
+		if x1 == x2 && y1 == y2 {
+			return points
+		}
+		if 2*e >= dy {
+			e += dy
+			x1 += sx
+		}
+		if 2*e <= dx {
+			e += dx
+			y1 += sy
+		}
+	}
+}
+
+// abs returns the absolute value of an int
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,90 @@
+package subbios
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/adamstimb/nimgobus/subbios/errorcode"
+)
+
+// Error is returned by the error-returning variants of the SUBBIOS functions.  It
+// wraps one of the errorcode constants and, if a parameter was to blame, the name
+// and value of that parameter.  Use errors.Is to test for a particular error code,
+// e.g. errors.Is(err, subbios.ErrInvalidParameter), or errors.As to get at the
+// details.
+type Error struct {
+	Function string // The name of the function that failed
+	Code     int    // The error code, e.g. errorcode.EInvalidParameter
+	Param    string // The name of the offending parameter (empty if not applicable)
+	Value    any    // The value of the offending parameter
+}
+
+// Sentinel errors for each error code, for use with errors.Is.
+var (
+	ErrTypeDoesntExist    = &Error{Code: errorcode.ETypeDoesntExist}
+	ErrDriverDoesntExist  = &Error{Code: errorcode.EDriverDoesntExist}
+	ErrFuncNotImplemented = &Error{Code: errorcode.EFuncNotImplemented}
+	ErrDiffSegs           = &Error{Code: errorcode.EDiffSegs}
+	ErrUnderflow          = &Error{Code: errorcode.EUnderflow}
+	ErrOverflow           = &Error{Code: errorcode.EOverflow}
+	ErrNotANumber         = &Error{Code: errorcode.ENotANumber}
+	ErrNotInitialized     = &Error{Code: errorcode.ENotInitialized}
+	ErrAlreadyOn          = &Error{Code: errorcode.EAlreadyOn}
+	ErrInvalidParameter   = &Error{Code: errorcode.EInvalidParameter}
+	ErrInterrupted        = &Error{Code: errorcode.EInterrupted}
+)
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	msg := errorcode.Text(e.Code)
+	if e.Function != "" {
+		msg = fmt.Sprintf("%s: %s", e.Function, msg)
+	}
+	if e.Param != "" {
+		msg = fmt.Sprintf("%s (%s=%v)", msg, e.Param, e.Value)
+	}
+	return msg
+}
+
+// Is reports whether target is an *Error with the same error code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return t.Code == e.Code
+}
+
+// newError returns an *Error for a function that failed without a particular
+// parameter being to blame.
+func newError(function string, code int) error {
+	return &Error{Function: function, Code: code}
+}
+
+// invalidParameter returns an EInvalidParameter *Error blaming param.
+func invalidParameter(function, param string, value any) error {
+	return &Error{Function: function, Code: errorcode.EInvalidParameter, Param: param, Value: value}
+}
+
+// notANumber returns an ENotANumber *Error blaming param.
+func notANumber(function, param string, value any) error {
+	return &Error{Function: function, Code: errorcode.ENotANumber, Param: param, Value: value}
+}
+
+// errorCode returns the error code wrapped by err, or EOk if err is nil.
+func errorCode(err error) int {
+	if err == nil {
+		return errorcode.EOk
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return errorcode.EInvalidParameter
+}
+
+// setFunctionError stores the error code wrapped by err in FunctionError for the
+// register-style functions.
+func (s *Subbios) setFunctionError(err error) {
+	s.FunctionError = errorCode(err)
+}
@@ -0,0 +1,48 @@
+// Package errorcode defines constants for the subbios error codes.
+package errorcode
+
+const (
+	EOk = iota
+	ETypeDoesntExist
+	EDriverDoesntExist
+	EFuncNotImplemented
+	EDiffSegs
+	EUnderflow
+	EOverflow
+	ENotANumber
+	ENotInitialized
+	EAlreadyOn
+	EInvalidParameter
+	EInterrupted // Not a SUBBIOS error: CTRL+C or CTRL+SHIFT+SCROLL LOCK broke in to Stdio's Write or Read
+)
+
+// Text returns a short description of an error code.
+func Text(code int) string {
+	switch code {
+	case EOk:
+		return "ok"
+	case ETypeDoesntExist:
+		return "type doesn't exist"
+	case EDriverDoesntExist:
+		return "driver doesn't exist"
+	case EFuncNotImplemented:
+		return "function not implemented"
+	case EDiffSegs:
+		return "different segments"
+	case EUnderflow:
+		return "underflow"
+	case EOverflow:
+		return "overflow"
+	case ENotANumber:
+		return "not a number"
+	case ENotInitialized:
+		return "not initialized"
+	case EAlreadyOn:
+		return "already on"
+	case EInvalidParameter:
+		return "invalid parameter"
+	case EInterrupted:
+		return "interrupted"
+	}
+	return "unknown error"
+}
@@ -1,6 +1,8 @@
 package subbios
 
 import (
+	"fmt"
+
 	"github.com/adamstimb/nimgobus/internal/make2darray"
 )
 
@@ -82,34 +84,12 @@ func (c *console) cursorNotVisible() {
 }
 
 func (c *console) setGraphicsRendition(params []int) {
+	if c.ansiMode {
+		c.setANSIGraphicsRendition(params)
+		return
+	}
 	for _, p := range params {
-		switch p {
-		case 0:
-			// all attributes off
-			c.underlined = false
-			c.charSet = 0
-			c.paperColour = 0
-			if c.v.screenWidth == 80 {
-				c.penColour = 1
-			} else {
-				c.penColour = 7
-			}
-			continue
-		case 4:
-			// underline on
-			c.underlined = true
-			continue
-		case 10:
-			// standard charset
-			c.charSet = 0
-			continue
-		case 11:
-			// alternative charset
-			c.charSet = 1
-			continue
-		case 24:
-			// underline off
-			c.underlined = false
+		if c.setAttribute(p) {
 			continue
 		}
 		// handle foreground/background colours
@@ -132,6 +112,87 @@ func (c *console) setGraphicsRendition(params []int) {
 	}
 }
 
+// setAttribute handles the SGR parameters that are the same in Nimbus and ANSI
+// modes, and returns false if p isn't one of them.  Bold, faint, reverse and
+// concealed are ignored in Nimbus mode unless they've been switched on with
+// SetAttributes.
+func (c *console) setAttribute(p int) bool {
+	switch p {
+	case 0:
+		// all attributes off
+		c.underlined = false
+		c.attributes = charAttributes{}
+		c.charSet = 0
+		c.paperColour = 0
+		c.penColour = c.defaultPenColour()
+		return true
+	case 1:
+		// bold on
+		if c.attributesOn || c.ansiMode {
+			c.attributes.bold = true
+			c.attributes.faint = false
+		}
+		return true
+	case 2:
+		// faint on
+		if c.attributesOn || c.ansiMode {
+			c.attributes.faint = true
+			c.attributes.bold = false
+		}
+		return true
+	case 4:
+		// underline on
+		c.underlined = true
+		return true
+	case 7:
+		// reverse video on
+		if c.attributesOn || c.ansiMode {
+			c.attributes.reverse = true
+		}
+		return true
+	case 8:
+		// concealed on
+		if c.attributesOn || c.ansiMode {
+			c.attributes.concealed = true
+		}
+		return true
+	case 10:
+		// standard charset
+		c.charSet = 0
+		return true
+	case 11:
+		// alternative charset
+		c.charSet = 1
+		return true
+	case 22:
+		// bold and faint off
+		c.attributes.bold = false
+		c.attributes.faint = false
+		return true
+	case 24:
+		// underline off
+		c.underlined = false
+		return true
+	case 27:
+		// reverse video off
+		c.attributes.reverse = false
+		return true
+	case 28:
+		// concealed off
+		c.attributes.concealed = false
+		return true
+	}
+	return false
+}
+
+// defaultPenColour returns the pen colour after SGR 0.
+func (c *console) defaultPenColour() int {
+	if c.v.screenWidth == 80 {
+		return 1
+	}
+	return 7
+}
+
 func (c *console) setColourLookupTable(q, n, m, f, p int) {
 	// validate
 	if q != 40 && q != 80 {
@@ -165,6 +226,7 @@ func (c *console) setMode(n int) {
 		// 40 column mode, clear screen, home cursor, use low-res CLT
 		c.v.waitForEmptyDrawQueue()
 		c.v.resetVideoMemory()
+		c.clearCells()
 		c.v.screenWidth = 40
 		c.syncVideoColourTable()
 		c.scrollingArea = [4]int{1, 1, 25, 40}
@@ -175,6 +237,7 @@ func (c *console) setMode(n int) {
 		// 80 column mode, clear screen, home cursor, use high-res CLT
 		c.v.waitForEmptyDrawQueue()
 		c.v.resetVideoMemory()
+		c.clearCells()
 		c.v.screenWidth = 80
 		c.syncVideoColourTable()
 		c.scrollingArea = [4]int{1, 1, 25, 80}
@@ -194,6 +257,7 @@ func (c *console) resetMode(n int) {
 		// 40 column mode, clear screen, home cursor, use low-res CLT
 		c.v.waitForEmptyDrawQueue()
 		c.v.resetVideoMemory()
+		c.clearCells()
 		c.syncVideoColourTable()
 		c.v.screenWidth = 40
 		c.scrollingArea = [4]int{1, 1, 25, 40}
@@ -203,6 +267,7 @@ func (c *console) resetMode(n int) {
 		// 80 column mode, clear screen, home cursor, use high-res CLT
 		c.v.waitForEmptyDrawQueue()
 		c.v.resetVideoMemory()
+		c.clearCells()
 		c.syncVideoColourTable()
 		c.v.screenWidth = 80
 		c.scrollingArea = [4]int{1, 1, 25, 80}
@@ -244,9 +309,12 @@ func (c *console) resetToInitialState() {
 	}
 	c.syncVideoColourTable()
 	c.v.screenWidth = 80
+	c.v.resetClippingAreas()
 	c.penColour = 1
 	c.paperColour = 0
 	c.charSet = 0
+	c.underlined = false
+	c.attributes = charAttributes{}
 	c.wordWrap = true
 	c.cursorChar = 95
 	c.cursorCharSet = 0
@@ -259,15 +327,95 @@ func (c *console) resetToInitialState() {
 	c.stdoutBuffer.Reset()
 	c.stdinBuffer.Reset()
 	c.stdinBufferIndex = 0
+	c.clearCells()
 }
 
 func (c *console) scrollUp(n int) {
-	// Define bounding rectangle for the textbox
+	// Handle unset params
+	if n < 1 {
+		n = 1
+	}
+	c.saveScrollback(n)
+	c.shiftRows(1, n)
+}
+
+func (c *console) scrollDown(n int) {
+	// Handle unset params
+	if n < 1 {
+		n = 1
+	}
+	c.shiftRows(1, -n)
+}
+
+// insertLine moves the row with the cursor and all the rows below it down n rows,
+// leaving n empty rows.  Rows pushed off the bottom of the scrolling area are lost.
+// Cursor does not move.
+func (c *console) insertLine(n int) {
+	// Handle unset params
+	if n < 1 {
+		n = 1
+	}
+	c.shiftRows(c.curpos[0], -n)
+}
+
+// deleteLine deletes n rows starting with the one with the cursor, moving the rows
+// below up and leaving empty rows at the bottom of the scrolling area.  Cursor does
+// not move.
+func (c *console) deleteLine(n int) {
+	// Handle unset params
+	if n < 1 {
+		n = 1
+	}
+	c.shiftRows(c.curpos[0], n)
+}
+
+// insertChar moves the char at the cursor and all the chars to the right of it
+// right n columns, leaving n blank chars.  Chars pushed off the end of the row are
+// lost.  Cursor does not move.
+func (c *console) insertChar(n int) {
+	// Handle unset params
+	if n < 1 {
+		n = 1
+	}
+	c.shiftColumns(c.curpos[1], -n)
+}
+
+// deleteChar deletes n chars starting with the one at the cursor, moving the rest of
+// the row left and leaving blank chars at the end of it.  Cursor does not move.
+func (c *console) deleteChar(n int) {
+	// Handle unset params
+	if n < 1 {
+		n = 1
+	}
+	c.shiftColumns(c.curpos[1], n)
+}
+
+// shiftRows moves the rows of the scrolling area from row top to the bottom up n
+// rows, or down if n is negative.  The rows left behind are filled with paper.
+func (c *console) shiftRows(top, n int) {
+	// Define bounding rectangle for the rows
 	h, w := c.getScrollingAreaSize()
-	x1, y1 := c.convertAnyCurposToXY(1, 1)
+	x1, y1 := c.convertAnyCurposToXY(top, 1)
 	x2, y2 := c.convertAnyCurposToXY(h, w)
 	y1 += 10
 	x2 += 8
+	shift := abs(n) * 10
+	if n == 0 || top < 1 || top > h {
+		return
+	}
+	if shift >= y1-y2 {
+		// Everything moves out of the way so it's just paper
+		c.eraseArea(top, 1, h, w)
+		return
+	}
+
+	// Going up the rows that survive are at the bottom, going down they're at the top
+	srcY, destY := y2, y2+shift
+	paperTop, paperBottom := h-n+1, h
+	if n < 0 {
+		srcY, destY = y2+shift, y2
+		paperTop, paperBottom = top, top-n-1
+	}
 
 	// We have to manipulate videoMemory itself next, so wait for drawQueue to empty and get the drawQueue lock
 	c.v.waitForEmptyDrawQueue()
@@ -276,28 +424,47 @@ func (c *console) scrollUp(n int) {
 	c.v.muDrawQueue.Lock()
 	c.v.muMemory.Lock()
 
-	// Copy the textbox segment of videoMemory
-	textBoxImg := make2darray.Make2dArray((x2-x1)+1, y1-y2, -1)
-	for y := y2; y < y1; y++ {
-		textBoxImg[(len(textBoxImg)-1)-(y-y2)] = c.v.memory[249-y][x1:x2]
+	// Copy the rows that survive
+	rowsImg := make2darray.Make2dArray((x2 - x1), (y1-y2)-shift, -1)
+	for i := range rowsImg {
+		copy(rowsImg[i], c.v.memory[249-(srcY+len(rowsImg)-1-i)][x1:x2])
 	}
-	// Empty paper on bottom row of textbox
-	paperImg := make2darray.Make2dArray((x2 - x1), 10, c.paperColour)
 
 	// Unlock everything and send images
 	c.v.muDrawQueue.Unlock()
 	c.v.muMemory.Unlock()
-	c.v.drawFeature(feature{pixels: textBoxImg[10:], x: x1, y: y2 + 10, colour: -1, xor: false})
-	c.v.drawFeature(feature{pixels: paperImg, x: x1, y: y2, colour: -1, xor: false})
+	c.v.drawFeature(feature{pixels: rowsImg, x: x1, y: destY, colour: -1, xor: false})
+	c.shiftCells(top, 1, h, w, n, 0)
+	c.eraseArea(paperTop, 1, paperBottom, w)
 }
 
-func (c *console) scrollDown(n int) {
-	// Define bounding rectangle for the textbox
-	h, w := c.getScrollingAreaSize()
-	x1, y1 := c.convertAnyCurposToXY(1, 1)
-	x2, y2 := c.convertAnyCurposToXY(h, w)
-	y1 += 10
+// shiftColumns moves the chars on the cursor's row from column left to the right
+// edge of the scrolling area left n columns, or right if n is negative.  The chars
+// left behind are filled with paper.
+func (c *console) shiftColumns(left, n int) {
+	// Define bounding rectangle for the chars
+	_, w := c.getScrollingAreaSize()
+	x1, y := c.convertAnyCurposToXY(c.curpos[0], left)
+	x2, _ := c.convertAnyCurposToXY(c.curpos[0], w)
 	x2 += 8
+	shift := abs(n) * 8
+	if n == 0 || left < 1 || left > w {
+		return
+	}
+	row := c.curpos[0]
+	if shift >= x2-x1 {
+		// Everything moves out of the way so it's just paper
+		c.eraseArea(row, left, row, w)
+		return
+	}
+
+	// Going left the chars that survive are on the right, going right they're on the left
+	srcX, destX := x1+shift, x1
+	paperLeft, paperRight := w-n+1, w
+	if n < 0 {
+		srcX, destX = x1, x1+shift
+		paperLeft, paperRight = left, left-n-1
+	}
 
 	// We have to manipulate videoMemory itself next, so wait for drawQueue to empty and get the drawQueue lock
 	c.v.waitForEmptyDrawQueue()
@@ -306,28 +473,18 @@ func (c *console) scrollDown(n int) {
 	c.v.muDrawQueue.Lock()
 	c.v.muMemory.Lock()
 
-	// Copy the textbox segment of videoMemory
-	textBoxImg := make2darray.Make2dArray((x2-x1)+1, y1-y2, -1)
-	for y := y2; y < y1; y++ {
-		textBoxImg[(len(textBoxImg)-1)-(y-y2)] = c.v.memory[249-y][x1:x2]
-	}
-
-	// Truncate bottom row of textbox
-	truncated := make2darray.Make2dArray(len(textBoxImg[0]), len(textBoxImg)-10, -1)
-	for y := 0; y < len(truncated); y++ {
-		for x := 0; x < len(truncated[0]); x++ {
-			truncated[y][x] = textBoxImg[y][x]
-		}
+	// Copy the chars that survive
+	charsImg := make2darray.Make2dArray((x2-x1)-shift, 10, -1)
+	for i := range charsImg {
+		copy(charsImg[i], c.v.memory[249-(y+9-i)][srcX:])
 	}
 
-	// Empty paper on *top* row of textbox
-	paperImg := make2darray.Make2dArray((x2 - x1), 10, c.paperColour)
-
 	// Unlock everything and send images
 	c.v.muDrawQueue.Unlock()
 	c.v.muMemory.Unlock()
-	c.v.drawFeature(feature{pixels: truncated, x: x1, y: y2, colour: -1, xor: false})
-	c.v.drawFeature(feature{pixels: paperImg, x: x1, y: y1 - 10, colour: -1, xor: false})
+	c.v.drawFeature(feature{pixels: charsImg, x: destX, y: y, colour: -1, xor: false})
+	c.shiftCells(row, left, row, w, 0, n)
+	c.eraseArea(row, paperLeft, row, paperRight)
 }
 
 func (c *console) cursorForward(n int) {
@@ -433,7 +590,7 @@ func (c *console) cursorPosition(r1, c1 int) {
 		return
 	}
 	// If col is within but row is outside go to c1 and top row:
-	if (col >= c.scrollingArea[1] && col <= c.scrollingArea[3]) && (row < c.scrollingArea[0] || row > c.scrollingArea[3]) {
+	if (col >= c.scrollingArea[1] && col <= c.scrollingArea[3]) && (row < c.scrollingArea[0] || row > c.scrollingArea[2]) {
 		c.curpos = [2]int{1, c1}
 		return
 	}
@@ -441,6 +598,30 @@ func (c *console) cursorPosition(r1, c1 int) {
 	c.curpos = [2]int{r1, c1}
 }
 
+// cursorHorizontalAbsolute moves the cursor to column n of the current row.
+func (c *console) cursorHorizontalAbsolute(n int) {
+	c.cursorPosition(c.curpos[0], n)
+}
+
+// linePositionAbsolute moves the cursor to row n of the current column.
+func (c *console) linePositionAbsolute(n int) {
+	c.cursorPosition(n, c.curpos[1])
+}
+
+// eraseCharacter erases n chars from the cursor onwards, stopping at the end of the
+// row.  Cursor does not move.
+func (c *console) eraseCharacter(n int) {
+	// Handle unset params
+	if n < 1 {
+		n = 1
+	}
+	_, w := c.getScrollingAreaSize()
+	if c.curpos[1]+n-1 > w {
+		n = w - c.curpos[1] + 1
+	}
+	c.eraseArea(c.curpos[0], c.curpos[1], c.curpos[0], c.curpos[1]+n-1)
+}
+
 func (c *console) saveCursorPosition() {
 	c.savedCurpos = [2]int{c.curpos[0], c.curpos[1]}
 }
@@ -453,107 +634,88 @@ func (c *console) cursorPositionReport() (row, col int) {
 	return c.curpos[0], c.curpos[1]
 }
 
+// deviceStatusReport answers a DSR by putting the reply in the stdin buffer, as
+// though it had been typed, so programs that only speak escape sequences can read it
+// with Getch:
+// n=5 replies ESC[0n (everything's fine).
+// n=6 replies with a CPR ESC[r;cR giving the cursor position within the scrolling
+// area.
+func (c *console) deviceStatusReport(n int) {
+	switch n {
+	case 5:
+		c.reply("\x1b[0n")
+	case 6:
+		row, col := c.cursorPositionReport()
+		c.reply(fmt.Sprintf("\x1b[%d;%dR", row, col))
+	}
+}
+
+// screenStatusReport answers ~H by putting ESC[m;t;l;b;r~H in the stdin buffer, where
+// m is the column mode as set by SM (0 for 40 columns, 2 for 80) and t, l, b, r is
+// the scrolling area as set by DSA.
+func (c *console) screenStatusReport() {
+	mode := 2
+	if c.v.screenWidth == 40 {
+		mode = 0
+	}
+	a := c.scrollingArea
+	c.reply(fmt.Sprintf("\x1b[%d;%d;%d;%d;%d~H", mode, a[0], a[1], a[2], a[3]))
+}
+
+// reply puts a reply to an escape sequence in the stdin buffer.
+func (c *console) reply(st string) {
+	for _, r := range st {
+		c.stdinBuffer.Enqueue(r)
+	}
+}
+
 func (c *console) eraseInDisplay(n int) {
+	// Handle unset params
+	if n < 0 {
+		n = 0
+	}
 	c.v.waitForEmptyDrawQueue()
 	h, w := c.getScrollingAreaSize()
 	switch n {
 	case 0:
 		// Erase chars from the cursor position to the end of the row (including the
 		// char at the cursor position), and all rows below. Cursor does not move.
-
-		// First call eraseInLine(0) to erase current row
 		c.eraseInLine(0)
-
-		// Then wipe all rows below if there are any:
-		if c.curpos[0] == h {
-			return
-		}
-
-		// Define bounding rectangle for the section to wipe:
-		x1, y1 := c.convertAnyCurposToXY(c.curpos[0], 1)
-		x2, y2 := c.convertAnyCurposToXY(h, w)
-		x2 += 8
-
-		// Draw empty paper
-		paperImg := make2darray.Make2dArray((x2 - x1), (y1 - y2), c.paperColour)
-		c.v.drawFeature(feature{pixels: paperImg, x: x1, y: y2, colour: -1, xor: false})
+		c.eraseArea(c.curpos[0]+1, 1, h, w)
 	case 1:
 		// Erase chars from the beginning of the row to the cursor (including the one
 		// at the cursor position), and all rows above. Cursor does not move.
-
-		// First call eraseInLine(1) to erase current row
 		c.eraseInLine(1)
-
-		// Then wipe all rows above if there are any:
-		if c.curpos[0] == 1 {
-			return
-		}
-
-		// Define bounding rectangle for the section to wipe:
-		x1, y1 := c.convertAnyCurposToXY(1, 1)
-		x2, y2 := c.convertAnyCurposToXY(h, w)
-		y1 += 10
-		x2 += 8
-
-		// Draw empty paper
-		paperImg := make2darray.Make2dArray((x2 - x1), (y1 - y2), c.paperColour)
-		c.v.drawFeature(feature{pixels: paperImg, x: x1, y: y2, colour: -1, xor: false})
+		c.eraseArea(1, 1, c.curpos[0]-1, w)
 	case 2:
 		// Erase entire scrolling area and send cursor home.
-
-		// Define bounding rectangle for the textbox:
-		x1, y1 := c.convertAnyCurposToXY(1, 1)
-		x2, y2 := c.convertAnyCurposToXY(h, w)
-		x2 += 8
-
-		// Draw empty paper over textbox and send cursor home
-		paperImg := make2darray.Make2dArray((x2 - x1), (y1-y2)+10, c.paperColour)
-		c.v.drawFeature(feature{pixels: paperImg, x: x1, y: y2, colour: -1, xor: false})
+		c.eraseArea(1, 1, h, w)
+		if c.ansiMode {
+			// ANSI leaves the cursor where it is
+			return
+		}
 		c.cursorPosition(1, 1)
 	}
 }
 
 func (c *console) eraseInLine(n int) {
+	// Handle unset params
+	if n < 0 {
+		n = 0
+	}
 	_, w := c.getScrollingAreaSize()
 	switch n {
 	case 0:
 		// Erase chars from the cursor position to the end of the row (including the
 		// char at the cursor position). Cursor does not move.
-
-		// Define bounding rectangle for the section to wipe:
-		x1, y1 := c.convertAnyCurposToXY(c.curpos[0], c.curpos[1])
-		x2, y2 := c.convertAnyCurposToXY(c.curpos[0], w)
-		y2 += 10
-		x2 += 8
-
-		// Draw empty paper
-		paperImg := make2darray.Make2dArray((x2 - x1), 10, c.paperColour)
-		c.v.drawFeature(feature{pixels: paperImg, x: x1, y: y1, colour: -1, xor: false})
+		c.eraseArea(c.curpos[0], c.curpos[1], c.curpos[0], w)
 	case 1:
 		// Erase chars from the beginning of the row to the cursor (including the one
 		// at the cursor position). Cursor does not move.
-
-		// Define bounding rectangle for the section to wipe:
-		x1, y1 := c.convertAnyCurposToXY(c.curpos[0], 1)
-		x2, y2 := c.convertAnyCurposToXY(c.curpos[0], c.curpos[1])
-		y2 += 10
-		x2 += 8
-
-		// Draw empty paper
-		paperImg := make2darray.Make2dArray((x2 - x1), 10, c.paperColour)
-		c.v.drawFeature(feature{pixels: paperImg, x: x1, y: y1, colour: -1, xor: false})
+		c.eraseArea(c.curpos[0], 1, c.curpos[0], c.curpos[1])
 	case 2:
 		// Erase whole row. Cursor does not move.
-
-		// Define bounding rectangle for the textbox:
-		x1, y1 := c.convertAnyCurposToXY(c.curpos[0], 1)
-		x2, y2 := c.convertAnyCurposToXY(c.curpos[0], w)
-		y1 += 10
-		x2 += 8
-
-		// Draw empty paper
-		paperImg := make2darray.Make2dArray((x2 - x1), 10, c.paperColour)
-		c.v.drawFeature(feature{pixels: paperImg, x: x1, y: y2, colour: -1, xor: false})
+		c.eraseArea(c.curpos[0], 1, c.curpos[0], w)
 	}
 }
 
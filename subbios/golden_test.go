@@ -0,0 +1,128 @@
+package subbios_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/adamstimb/nimgobus/subbios/subbiostest"
+)
+
+func TestGoldenPolyLine(t *testing.T) {
+	s := subbiostest.New(t)
+	s.Stdio.Printf("\x1b~F") // Hide cursor
+	for style := 1; style <= 5; style++ {
+		y := 240 - (style * 40)
+		s.TGraphicsOutput.FPolyLine(style, []int{0}, 3, 0, 1, []int{10, y, 300, y + 30, 620, y})
+	}
+	s.TGraphicsOutput.FPolyLine(0, []int{4}, 256, 0, 0, []int{0, 0, 639, 249})
+	subbiostest.AssertGolden(t, s, "polyline")
+}
+
+func TestGoldenFillArea(t *testing.T) {
+	s := subbiostest.New(t)
+	s.Stdio.Printf("\x1b0h\x1b~F") // Mode 40, hide cursor
+	s.TGraphicsOutput.FFillArea(0, 0, 15, 0, 0, []int{10, 10, 100, 10, 55, 100})
+	s.TGraphicsOutput.FFillArea(1, 0, 9, 0, 0, []int{110, 10, 200, 10, 155, 100})
+	s.TGraphicsOutput.FFillArea(2, 3, 0, 0, 0, []int{210, 10, 300, 10, 255, 100})
+	s.TGraphicsOutput.FFillArea(3, 2, 14, 1, 0, []int{10, 120, 150, 120, 150, 240, 10, 240})
+	s.TGraphicsOutput.FFillArea(1, 0, 256+6, 0, 0, []int{100, 150, 300, 150, 300, 200, 100, 200})
+	subbiostest.AssertGolden(t, s, "fillarea")
+}
+
+func TestGoldenPieSlice(t *testing.T) {
+	s := subbiostest.New(t)
+	s.Stdio.Printf("\x1b~F") // Hide cursor
+	s.TGraphicsOutput.FPieSlice(150, 125, 100, 0, 0, 1)
+	s.TGraphicsOutput.FPieSlice(450, 125, 100, 1571, 4712, 2)
+	subbiostest.AssertGolden(t, s, "pieslice")
+}
+
+func TestGoldenStyledPieSlice(t *testing.T) {
+	s := subbiostest.New(t)
+	s.Stdio.Printf("\x1b~F") // Hide cursor
+	s.TGraphicsOutput.FStyledPieSlice(3, 2, 1, 0, 1, 2, []int{0}, 3, 0, 1, 80, 125, 50, 0, 1571)
+	s.TGraphicsOutput.FStyledPieSlice(3, 4, 2, 0, 1, 1, []int{0}, 3, 0, 0, 80, 125, 50, 1571, 4000)
+	s.TGraphicsOutput.FStyledPieSlice(2, 5, 3, 0, 0, 3, []int{0}, 1, 2, 0, 80, 125, 50, 4000, 0)
+	s.TGraphicsOutput.FStyledPieSlice(0, 0, 2, 0, 0, -1, nil, 0, 0, 0, 320, 125, 100, 0, 0)
+	s.TGraphicsOutput.FStyledPieSlice(1, 0, 256+3, 0, 0, 1, []int{0}, 256+1, 0, 0, 320, 125, 60, 0, 0)
+	s.TGraphicsOutput.FStyledPieSlice(1, 0, 256+3, 0, 0, 1, []int{0}, 256+1, 0, 0, 320, 125, 60, 0, 0) // Rubs it out
+	s.TGraphicsOutput.FStyledPieSlice(1, 0, 1, 0, 0, 1, []int{0}, 256+2, 0, 0, 520, 125, 60, 1000, 5000)
+	subbiostest.AssertGolden(t, s, "styledpieslice")
+}
+
+func TestGoldenEllipse(t *testing.T) {
+	s := subbiostest.New(t)
+	s.Stdio.Printf("\x1b~F") // Hide cursor
+	s.TGraphicsOutput.FArcOfEllipse(1, []int{0}, 3, 0, 0, 100, 125, 90, 60, 0, 0)
+	s.TGraphicsOutput.FArcOfEllipse(2, []int{0}, 2, 0, 1, 100, 125, 60, 100, 1571, 4712)
+	s.TGraphicsOutput.FEllipseSlice(3, 1, 1, 2, 0, 320, 125, 100, 50, 0, 0)
+	s.TGraphicsOutput.FEllipseSlice(2, 3, 3, 0, 0, 520, 125, 110, 100, 5000, 1000)
+	s.TGraphicsOutput.FEllipseSlice(1, 0, 256+3, 0, 0, 320, 125, 40, 110, 0, 3142)
+	s.TGraphicsOutput.FArcOfEllipse(1, []int{0}, 1, 0, 0, 620, 20, 60, 60, 0, 0) // Clipped
+	subbiostest.AssertGolden(t, s, "ellipse")
+}
+
+func TestGoldenPlotCharacterString(t *testing.T) {
+	s := subbiostest.New(t)
+	s.Stdio.Printf("\x1b~F") // Hide cursor
+	for orientation := 0; orientation < 4; orientation++ {
+		s.TGraphicsOutput.FPlotCharacterString(orientation, 2, 2, 3, 0, "Nimbus", 320, 125)
+	}
+	s.TGraphicsOutput.FPlotCharacterString(0, 1, 1, 1, 1, "Alternative charset", 10, 10)
+	s.TGraphicsOutput.FPlotCharacterString(0, 3, 1, 256+2, 0, "XOR", 10, 220)
+	s.TGraphicsOutput.FPlotCharacterString(0, 3, 1, 256+2, 0, "XOR", 14, 220)
+	subbiostest.AssertGolden(t, s, "plotcharacterstring")
+}
+
+func TestGoldenConsole(t *testing.T) {
+	s := subbiostest.New(t)
+	s.Stdio.Printf("\x1b~F")                  // Hide cursor
+	s.Stdio.Printf("Hello\tthere\n")          // Tab and newline
+	s.Stdio.Printf("\x1b[33;40mInverted\n")   // Pen and paper colours
+	s.Stdio.Printf("\x1b[0m\x1b[4mUnderline") // Reset then underline
+	s.Stdio.Printf("\x1b[10;20H\x1b[2mMoved") // Cursor position
+	s.Stdio.Printf("\x1b[12;1H" + "0123456789\x1b[12;5H\x1b[0K")
+	s.Stdio.Printf("\x1b[5;5;10;40~B\x1b[2J") // Scrolling area then clear it
+	for i := 0; i < 8; i++ {
+		s.Stdio.Printf("Scrolling line\n")
+	}
+	subbiostest.AssertGolden(t, s, "console")
+}
+
+func TestGoldenInsertDelete(t *testing.T) {
+	s := subbiostest.New(t)
+	s.Stdio.Printf("\x1b~F")                  // Hide cursor
+	s.Stdio.Printf("\x1b[3;3;14;30~B\x1b[2J") // Scrolling area then clear it
+	for i := 1; i <= 10; i++ {
+		s.Stdio.Printf(fmt.Sprintf("Line %d\n", i))
+	}
+	s.Stdio.Printf("\x1b[2S")                           // Scroll up 2
+	s.Stdio.Printf("\x1b[3;1H\x1b[2L")                  // Insert 2 lines at row 3
+	s.Stdio.Printf("\x1b[9;1H\x1b[M")                   // Delete 1 line at row 9
+	s.Stdio.Printf("\x1b[1;1HABCDEFGH\x1b[1;3H\x1b[3@") // Insert 3 chars before C
+	s.Stdio.Printf("\x1b[2;1HABCDEFGH\x1b[2;3H\x1b[2P") // Delete C and D
+	subbiostest.AssertGolden(t, s, "insertdelete")
+}
+
+func TestGoldenAttributes(t *testing.T) {
+	s := subbiostest.New(t)
+	s.Stdio.SetAttributes(true)
+	s.Stdio.Printf("\x1b~F\x1b0h")                            // Hide cursor, mode 40
+	s.Stdio.Printf("\x1b[33mNormal \x1b[1mBold\x1b[22m\n")    // Brown then yellow
+	s.Stdio.Printf("\x1b[41mNormal \x1b[2mFaint\x1b[22m\n")   // Yellow then brown
+	s.Stdio.Printf("\x1b[37;51m\x1b[7mReverse\x1b[27m\n")     // Red on grey
+	s.Stdio.Printf("Hidden:\x1b[8mSecret\x1b[28m:Revealed\n") // Grey on red with a gap
+	s.Stdio.Printf("\x1b[1;7mBoth\x1b[0m Reset")
+	subbiostest.AssertGolden(t, s, "attributes")
+}
+
+func TestGoldenANSI(t *testing.T) {
+	s := subbiostest.New(t)
+	s.Stdio.Printf("\x1b[0h\x1b[1~I")           // Mode 40, ANSI mode on
+	s.Stdio.Printf("\x1b[?25l\x1b]0;Title\x07") // Hide cursor, set the title
+	s.Stdio.Printf("\x1b[01;34mdir\x1b[0m  \x1b[01;32mexe\x1b[0m  file\n")
+	s.Stdio.Printf("\x1b[31mred \x1b[91mbright \x1b[43;30myellow\x1b(B\x1b[m [brackets]\n")
+	s.Stdio.Printf("\x1b[38;5;21m256 \x1b[38;2;255;255;255;48;5;28mtrue\x1b[39;49m colour\n")
+	s.Stdio.Printf("\x1b[1;7mbold reverse\x1b[m\x1b[0~I\x1b[37;54m Nimbus again")
+	subbiostest.AssertGolden(t, s, "ansi")
+}
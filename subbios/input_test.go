@@ -0,0 +1,22 @@
+package subbios
+
+// testInput is an Input with keys that have been held down for a number of updates
+// and chars just typed.
+type testInput struct {
+	keys  map[Key]int
+	chars string
+}
+
+func (in testInput) AppendInputChars(runes []rune) []rune  { return append(runes, []rune(in.chars)...) }
+func (in testInput) CursorPosition() (x, y int)            { return 0, 0 }
+func (in testInput) IsMouseButtonPressed(MouseButton) bool { return false }
+func (in testInput) KeyPressDuration(key Key) int          { return in.keys[key] }
+
+// pressed returns keys that have just gone down, for a testInput.
+func pressed(keys ...Key) map[Key]int {
+	m := map[Key]int{}
+	for _, k := range keys {
+		m[k] = 1
+	}
+	return m
+}
@@ -0,0 +1,270 @@
+package subbios
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Key codes are what Getch and Getchar return for keys that don't type a char.  The
+// cursor keys are \x01 to \x04, as they always were, ENTER, BACKSPACE, TAB and ESC are
+// the ASCII control chars and everything else is in the Unicode private use area so
+// it can't be mistaken for anything typed.  The codes the Nimbus keyboard sent for
+// its function keys have been lost in the mists of time, so these don't pretend to be
+// them.
+const (
+	KeyCodeLeft      rune = '\x01'
+	KeyCodeRight     rune = '\x02'
+	KeyCodeUp        rune = '\x03'
+	KeyCodeDown      rune = '\x04'
+	KeyCodeBackspace rune = '\b'
+	KeyCodeTab       rune = '\t'
+	KeyCodeEnter     rune = '\n'
+	KeyCodeEscape    rune = '\x1b'
+)
+
+// keyCodeBase is the start of the key codes in the private use area.  A code is
+// keyCodeBase + modifiers<<8 + key, where key is an ASCII code (upper case for
+// letters) or 0x80 and above for the keys that haven't got one.
+const keyCodeBase rune = 0xe000
+
+// More key codes, for the keys that haven't got an ASCII one.
+const (
+	KeyCodeF1 rune = keyCodeBase + 0x80 + iota
+	KeyCodeF2
+	KeyCodeF3
+	KeyCodeF4
+	KeyCodeF5
+	KeyCodeF6
+	KeyCodeF7
+	KeyCodeF8
+	KeyCodeF9
+	KeyCodeF10
+	KeyCodeF11
+	KeyCodeF12
+	KeyCodeHome
+	KeyCodeEnd
+	KeyCodePageUp
+	KeyCodePageDown
+	KeyCodeInsert
+	KeyCodeDelete
+)
+
+// Modifier is a set of the modifier keys held down with a key.
+type Modifier int
+
+// The modifier keys.
+const (
+	ModShift Modifier = 1 << iota
+	ModCtrl
+	ModAlt
+)
+
+// The line editor moves a word at a time with these.
+const (
+	keyCodeWordLeft  = keyCodeBase + rune(ModCtrl)<<8 + KeyCodeLeft
+	keyCodeWordRight = keyCodeBase + rune(ModCtrl)<<8 + KeyCodeRight
+)
+
+// keyCodeNames are the names of the key codes for KeyEvent.String.
+var keyCodeNames = map[rune]string{
+	KeyCodeLeft: "LEFT", KeyCodeRight: "RIGHT", KeyCodeUp: "UP", KeyCodeDown: "DOWN",
+	KeyCodeBackspace: "BACKSPACE", KeyCodeTab: "TAB", KeyCodeEnter: "ENTER", KeyCodeEscape: "ESC",
+	KeyCodeF1: "F1", KeyCodeF2: "F2", KeyCodeF3: "F3", KeyCodeF4: "F4", KeyCodeF5: "F5", KeyCodeF6: "F6",
+	KeyCodeF7: "F7", KeyCodeF8: "F8", KeyCodeF9: "F9", KeyCodeF10: "F10", KeyCodeF11: "F11", KeyCodeF12: "F12",
+	KeyCodeHome: "HOME", KeyCodeEnd: "END", KeyCodePageUp: "PAGE UP", KeyCodePageDown: "PAGE DOWN",
+	KeyCodeInsert: "INS", KeyCodeDelete: "DEL", ' ': "SPACE",
+}
+
+// WithModifiers returns the code for the key code pressed with mods held down, e.g.
+// WithModifiers('s', ModCtrl) is what Getch returns for CTRL+S.  Only ASCII chars and
+// key codes can have modifiers: anything else is returned as it is.
+func WithModifiers(code rune, mods Modifier) rune {
+	code, oldMods := SplitKeyCode(code)
+	mods |= oldMods
+	if mods == 0 {
+		return code
+	}
+	key := unicode.ToUpper(code)
+	if code >= keyCodeBase {
+		key = code - keyCodeBase
+	}
+	if key >= 0x100 || (key >= 0x80 && code < keyCodeBase) {
+		return code
+	}
+	return keyCodeBase + rune(mods)<<8 + key
+}
+
+// SplitKeyCode splits what Getch or Getchar returned into a key code and the modifiers
+// that were held down with it.  Letters come back in upper case if there were any
+// modifiers.
+func SplitKeyCode(r rune) (code rune, mods Modifier) {
+	if r < keyCodeBase || r >= keyCodeBase+0x800 {
+		return r, 0
+	}
+	mods = Modifier((r - keyCodeBase) >> 8)
+	key := (r - keyCodeBase) & 0xff
+	if key >= 0x80 {
+		return keyCodeBase + key, mods
+	}
+	return key, mods
+}
+
+// KeyEvent is a key from the keyboard buffer, taken apart.
+type KeyEvent struct {
+	Code      rune     // A char or one of the KeyCode constants, without the modifiers
+	Rune      rune     // The char typed, or 0 if the key doesn't type one (or CTRL or ALT was held down)
+	Modifiers Modifier // The modifiers held down, although SHIFT only counts for keys that don't type a char
+}
+
+// newKeyEvent takes apart r from the keyboard buffer.
+func newKeyEvent(r rune) KeyEvent {
+	code, mods := SplitKeyCode(r)
+	e := KeyEvent{Code: code, Modifiers: mods}
+	if mods == 0 && code >= ' ' && code < keyCodeBase {
+		e.Rune = code
+	}
+	return e
+}
+
+// String returns the name of the key with its modifiers, e.g. "CTRL+SHIFT+F1".
+func (e KeyEvent) String() string {
+	var b strings.Builder
+	for _, m := range []struct {
+		mod  Modifier
+		name string
+	}{{ModCtrl, "CTRL+"}, {ModAlt, "ALT+"}, {ModShift, "SHIFT+"}} {
+		if e.Modifiers&m.mod != 0 {
+			b.WriteString(m.name)
+		}
+	}
+	if name, ok := keyCodeNames[e.Code]; ok {
+		b.WriteString(name)
+	} else {
+		b.WriteRune(e.Code)
+	}
+	return b.String()
+}
+
+// ReadKey is Getchar for KeyEvents: it waits for a key and returns it.  If there's a
+// keyboard interrupt you get an empty KeyEvent.
+func (sio *Stdio) ReadKey() KeyEvent {
+	if r := sio.Getchar(); r != 0 {
+		return newKeyEvent(r)
+	}
+	return KeyEvent{}
+}
+
+// PollKey is Getch for KeyEvents: it returns the next key and true, or false if there
+// aren't any.
+func (sio *Stdio) PollKey() (KeyEvent, bool) {
+	_ = sio.GetCtrlCInterrupt(true)
+	_ = sio.GetCtrlShiftScrollLockInterrupt(true)
+	if sio.gotAnyInterrupts() {
+		return KeyEvent{}, false
+	}
+	r, ok := sio.c.stdinBuffer.Dequeue()
+	if !ok || r == 0 {
+		time.Sleep(5 * time.Millisecond)
+		return KeyEvent{}, false
+	}
+	return newKeyEvent(r), true
+}
+
+// consoleKeys are the keys that go in the keyboard buffer as key codes.
+var consoleKeys = []struct {
+	key  Key
+	code rune
+}{
+	{KeyEnter, KeyCodeEnter},
+	{KeyBackspace, KeyCodeBackspace},
+	{KeyTab, KeyCodeTab},
+	{KeyEscape, KeyCodeEscape},
+	{KeyLeft, KeyCodeLeft},
+	{KeyRight, KeyCodeRight},
+	{KeyUp, KeyCodeUp},
+	{KeyDown, KeyCodeDown},
+	{KeyHome, KeyCodeHome},
+	{KeyEnd, KeyCodeEnd},
+	{KeyPageUp, KeyCodePageUp},
+	{KeyPageDown, KeyCodePageDown},
+	{KeyInsert, KeyCodeInsert},
+	{KeyDelete, KeyCodeDelete},
+	{KeyF1, KeyCodeF1},
+	{KeyF2, KeyCodeF2},
+	{KeyF3, KeyCodeF3},
+	{KeyF4, KeyCodeF4},
+	{KeyF5, KeyCodeF5},
+	{KeyF6, KeyCodeF6},
+	{KeyF7, KeyCodeF7},
+	{KeyF8, KeyCodeF8},
+	{KeyF9, KeyCodeF9},
+	{KeyF10, KeyCodeF10},
+	{KeyF11, KeyCodeF11},
+	{KeyF12, KeyCodeF12},
+}
+
+// charKeys are the keys that type chars, which are only looked at if CTRL or ALT is
+// held down since the chars don't come through then.
+var charKeys = []struct {
+	key  Key
+	char rune
+}{
+	{KeyA, 'A'}, {KeyB, 'B'}, {KeyC, 'C'}, {KeyD, 'D'}, {KeyE, 'E'}, {KeyF, 'F'}, {KeyG, 'G'},
+	{KeyH, 'H'}, {KeyI, 'I'}, {KeyJ, 'J'}, {KeyK, 'K'}, {KeyL, 'L'}, {KeyM, 'M'}, {KeyN, 'N'},
+	{KeyO, 'O'}, {KeyP, 'P'}, {KeyQ, 'Q'}, {KeyR, 'R'}, {KeyS, 'S'}, {KeyT, 'T'}, {KeyU, 'U'},
+	{KeyV, 'V'}, {KeyW, 'W'}, {KeyX, 'X'}, {KeyY, 'Y'}, {KeyZ, 'Z'},
+	{Key0, '0'}, {Key1, '1'}, {Key2, '2'}, {Key3, '3'}, {Key4, '4'},
+	{Key5, '5'}, {Key6, '6'}, {Key7, '7'}, {Key8, '8'}, {Key9, '9'},
+}
+
+// heldModifiers returns the modifier keys that are held down.
+func heldModifiers(in Input) Modifier {
+	var mods Modifier
+	if in.KeyPressDuration(KeyShift) > 0 {
+		mods |= ModShift
+	}
+	if in.KeyPressDuration(KeyControl) > 0 {
+		mods |= ModCtrl
+	}
+	if in.KeyPressDuration(KeyAlt) > 0 {
+		mods |= ModAlt
+	}
+	return mods
+}
+
+// keyCodes returns the key codes for the keys pressed since the last update, in the
+// order of consoleKeys then charKeys.  Chars are typed as they are, unless CTRL or ALT
+// is held down and none come through, in which case the keys that were pressed get
+// key codes instead.
+func keyCodes(in Input) []rune {
+	codes := []rune{}
+	mods := heldModifiers(in)
+	for _, k := range consoleKeys {
+		if !repeatingKeyPressed(in, k.key) {
+			continue
+		}
+		m := mods
+		if k.code == KeyCodeEnter || k.code == KeyCodeBackspace {
+			// SHIFT doesn't change these
+			m &^= ModShift
+		}
+		codes = append(codes, WithModifiers(k.code, m))
+	}
+	if mods&(ModCtrl|ModAlt) == 0 {
+		return codes
+	}
+	for _, r := range in.AppendInputChars(nil) {
+		if r != 0 {
+			// ALT GR and friends
+			return codes
+		}
+	}
+	for _, k := range charKeys {
+		// CTRL+C is the keyboard interrupt
+		if repeatingKeyPressed(in, k.key) && !(k.char == 'C' && mods == ModCtrl) {
+			codes = append(codes, WithModifiers(k.char, mods))
+		}
+	}
+	return codes
+}
@@ -0,0 +1,74 @@
+package subbios
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyCodes(t *testing.T) {
+	tests := []struct {
+		in   testInput
+		want string // KeyEvent.String
+	}{
+		{testInput{chars: "a"}, "a"},
+		{testInput{keys: pressed(KeyEnter)}, "ENTER"},
+		{testInput{keys: pressed(KeyShift, KeyEnter)}, "ENTER"},
+		{testInput{keys: pressed(KeyF1)}, "F1"},
+		{testInput{keys: pressed(KeyShift, KeyTab)}, "SHIFT+TAB"},
+		{testInput{keys: pressed(KeyControl, KeyLeft)}, "CTRL+LEFT"},
+		{testInput{keys: pressed(KeyControl, KeyS)}, "CTRL+S"},
+		{testInput{keys: pressed(KeyControl, KeyAlt, KeyShift, KeyDelete)}, "CTRL+ALT+SHIFT+DEL"},
+		{testInput{keys: pressed(KeyAlt, Key7)}, "ALT+7"},
+		{testInput{keys: pressed(KeyControl, KeyAlt, KeyQ), chars: "@"}, "@"}, // ALT GR on some keyboards
+		{testInput{keys: pressed(KeyPageDown)}, "PAGE DOWN"},
+	}
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	sio := &s.Stdio
+	for _, test := range tests {
+		sio.c.update(test.in)
+		e, ok := sio.PollKey()
+		if !ok {
+			t.Errorf("%v didn't put anything in the keyboard buffer, want %s", test.in, test.want)
+			continue
+		}
+		if got := e.String(); got != test.want {
+			t.Errorf("%v gave %s, want %s", test.in, got, test.want)
+		}
+	}
+	// CTRL+C is the interrupt, not a key
+	sio.c.update(testInput{keys: pressed(KeyControl, KeyC)})
+	if e, ok := sio.PollKey(); ok {
+		t.Errorf("CTRL+C gave %s, want nothing", e)
+	}
+	// Everything pressed in one frame is queued, key codes first
+	sio.c.update(testInput{keys: pressed(KeyLeft, KeyEnter), chars: "ab"})
+	got := []string{}
+	for e, ok := sio.PollKey(); ok; e, ok = sio.PollKey() {
+		got = append(got, e.String())
+	}
+	if want := []string{"ENTER", "LEFT", "a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LEFT and ENTER with ab typed gave %q, want %q", got, want)
+	}
+}
+
+func TestWithModifiers(t *testing.T) {
+	tests := []struct {
+		code rune
+		mods Modifier
+		want KeyEvent
+	}{
+		{'x', 0, KeyEvent{Code: 'x', Rune: 'x'}},
+		{'x', ModCtrl, KeyEvent{Code: 'X', Modifiers: ModCtrl}},
+		{KeyCodeUp, 0, KeyEvent{Code: KeyCodeUp}},
+		{KeyCodeUp, ModShift | ModAlt, KeyEvent{Code: KeyCodeUp, Modifiers: ModShift | ModAlt}},
+		{WithModifiers(KeyCodeF12, ModCtrl), ModShift, KeyEvent{Code: KeyCodeF12, Modifiers: ModCtrl | ModShift}},
+		{'£', ModCtrl, KeyEvent{Code: '£', Rune: '£'}}, // Not ASCII so no modifiers
+	}
+	for _, test := range tests {
+		if got := newKeyEvent(WithModifiers(test.code, test.mods)); got != test.want {
+			t.Errorf("WithModifiers(%q, %d) gave %+v, want %+v", test.code, test.mods, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package subbios
+
+import (
+	"time"
+	"unicode"
+)
+
+// ScanfOptions change how ScanfWithOptions edits a line.  The zero value gives you
+// plain old Scanf.
+type ScanfOptions struct {
+	History        *History                   // Lines entered at this prompt, recalled with UP and DOWN (nil for none)
+	MaxLength      int                        // The most chars the line can have (0 for no limit)
+	Filter         func(rune) bool            // Chars are only accepted if this returns true (nil accepts everything)
+	Validator      func(line string) bool     // Edits are only accepted if this returns true for the line they'd make (nil accepts everything)
+	Completer      func(line string) []string // Returns what the line up to the cursor could be completed to when TAB is pressed (nil for no completion)
+	ShowCandidates bool                       // Set to true to list the candidates below the line if TAB can't decide between them
+}
+
+// History is a ring of the lines entered at a prompt, for ScanfWithOptions to recall
+// with UP and DOWN.  Give each prompt its own.
+type History struct {
+	lines []string
+	size  int
+}
+
+// NewHistory returns an empty History that keeps the last size lines, or none if
+// size is 0 or less.
+func NewHistory(size int) *History {
+	if size < 0 {
+		size = 0
+	}
+	return &History{size: size}
+}
+
+// Add puts line at the end of the history, dropping the oldest line if it's full.
+// Empty lines and repeats of the last line aren't added.
+func (h *History) Add(line string) {
+	if line == "" || (len(h.lines) > 0 && h.lines[len(h.lines)-1] == line) {
+		return
+	}
+	h.lines = append(h.lines, line)
+	if extra := len(h.lines) - h.size; extra > 0 {
+		h.lines = append([]string{}, h.lines[extra:]...)
+	}
+}
+
+// Lines returns a copy of the lines in the history, oldest first.
+func (h *History) Lines() []string {
+	return append([]string{}, h.lines...)
+}
+
+// ScanfWithOptions is Scanf with a proper line editor.  As well as LEFT, RIGHT and
+// BACKSPACE there's HOME and END, DEL to delete forwards, CTRL+LEFT and CTRL+RIGHT to
+// move a word at a time and INS to switch between inserting and overwriting.  UP and
+// DOWN go through opts.History, and the line is added to it when ENTER is pressed.
+// TAB replaces the line up to the cursor with what opts.Completer returns if there's
+// only one candidate, or else with as much as the candidates have in common, and if
+// that's no help and opts.ShowCandidates is set they're listed below the line until
+// the next key.  Anything that would make a line opts.Validator doesn't like rings the
+// bell instead.
+// The line can be longer than the scrolling area is wide, in which case it wraps and
+// scrolls as necessary.  When ENTER is pressed the cursor is left at the end of the
+// line.
+func (sio *Stdio) ScanfWithOptions(textBuffer *[]rune, opts ScanfOptions) {
+	_ = sio.GetCtrlCInterrupt(true)
+	_ = sio.GetCtrlShiftScrollLockInterrupt(true)
+	e := &lineEditor{
+		c:     sio.c,
+		opts:  opts,
+		line:  append([]rune{}, *textBuffer...),
+		start: sio.c.curpos,
+	}
+	if opts.History != nil {
+		e.historyIndex = len(opts.History.lines)
+	}
+	// Remember what's before the line, e.g. a prompt, in case it's scrolled away
+	for col := 1; col < e.start[1]; col++ {
+		e.prefix = append(e.prefix, sio.c.getCell(e.start[0], col))
+	}
+	oldCursorDisplayed := sio.c.cursorDisplayed
+	defer func() { sio.c.cursorDisplayed = oldCursorDisplayed }()
+	e.update(0, len(e.line))
+
+	for {
+		if sio.gotAnyInterrupts() {
+			e.hideCandidates()
+			return
+		}
+		r, ok := sio.c.stdinBuffer.Dequeue()
+		if !ok || r == 0 {
+			time.Sleep(5 * time.Millisecond)
+			continue
+		}
+		sio.c.cursorDisplayed = false
+		e.hideCandidates()
+		if r == '\n' {
+			e.update(len(e.line), len(e.line))
+			if opts.History != nil {
+				opts.History.Add(string(e.line))
+			}
+			*textBuffer = e.line
+			return
+		}
+		if !e.key(r) {
+			sio.c.bell()
+		}
+		sio.c.cursorDisplayed = oldCursorDisplayed
+	}
+}
+
+// lineEditor is the state of a line being edited by ScanfWithOptions.
+type lineEditor struct {
+	c            *console
+	opts         ScanfOptions
+	line         []rune   // The line so far
+	index        int      // Where the cursor is in the line
+	start        [2]int   // The curpos of the start of the line, which is above the scrolling area if its row is < 1
+	prefix       []Cell   // What's on the row before the start of the line
+	drawn        int      // How much of the line is on the screen, so the rest can be erased if it gets shorter
+	overwrite    bool     // Set to true when typing overwrites instead of inserting
+	historyIndex int      // Which history line is being edited, or the number of lines for a new one
+	draft        []rune   // The new line, kept while going through the history
+	candidates   [][]Cell // What was on the rows the candidates are listed on
+	candidateRow int      // The first row of the candidates list
+}
+
+// key edits the line according to r and returns false if r can't be done.
+func (e *lineEditor) key(r rune) bool {
+	switch r {
+	case KeyCodeLeft:
+		return e.moveTo(e.index - 1)
+	case KeyCodeRight:
+		return e.moveTo(e.index + 1)
+	case KeyCodeHome:
+		return e.moveTo(0)
+	case KeyCodeEnd:
+		return e.moveTo(len(e.line))
+	case keyCodeWordLeft:
+		i := e.index
+		for i > 0 && unicode.IsSpace(e.line[i-1]) {
+			i--
+		}
+		for i > 0 && !unicode.IsSpace(e.line[i-1]) {
+			i--
+		}
+		return e.moveTo(i)
+	case keyCodeWordRight:
+		i := e.index
+		for i < len(e.line) && !unicode.IsSpace(e.line[i]) {
+			i++
+		}
+		for i < len(e.line) && unicode.IsSpace(e.line[i]) {
+			i++
+		}
+		return e.moveTo(i)
+	case KeyCodeBackspace:
+		if e.index == 0 {
+			return false
+		}
+		return e.edit(splice(e.line, e.index-1, e.index), e.index-1, e.index-1)
+	case KeyCodeDelete:
+		if e.index == len(e.line) {
+			return false
+		}
+		return e.edit(splice(e.line, e.index, e.index+1), e.index, e.index)
+	case KeyCodeTab:
+		return e.complete()
+	case KeyCodeInsert:
+		e.overwrite = !e.overwrite
+	case KeyCodeUp:
+		return e.recall(e.historyIndex - 1)
+	case KeyCodeDown:
+		return e.recall(e.historyIndex + 1)
+	default:
+		if r < ' ' || (r >= keyCodeBase && r < keyCodeBase+0x800) {
+			// Some other key that's no use here
+			return false
+		}
+		if e.opts.Filter != nil && !e.opts.Filter(r) {
+			return false
+		}
+		if e.overwrite && e.index < len(e.line) {
+			return e.edit(splice(e.line, e.index, e.index+1, r), e.index, e.index+1)
+		}
+		if e.opts.MaxLength > 0 && len(e.line) >= e.opts.MaxLength {
+			return false
+		}
+		return e.edit(splice(e.line, e.index, e.index, r), e.index, e.index+1)
+	}
+	return true
+}
+
+// splice returns a new line with line[i:j] replaced by runes.
+func splice(line []rune, i, j int, runes ...rune) []rune {
+	newLine := append([]rune{}, line[:i]...)
+	newLine = append(newLine, runes...)
+	return append(newLine, line[j:]...)
+}
+
+// edit replaces the line with line and moves the cursor to index, redrawing from
+// changed onwards, or returns false if the validator won't have it.
+func (e *lineEditor) edit(line []rune, changed, index int) bool {
+	if e.opts.Validator != nil && !e.opts.Validator(string(line)) {
+		return false
+	}
+	e.line = line
+	e.update(changed, index)
+	return true
+}
+
+// complete does TAB, returning false if there's nothing to be done.
+func (e *lineEditor) complete() bool {
+	if e.opts.Completer == nil {
+		return false
+	}
+	candidates := e.opts.Completer(string(e.line[:e.index]))
+	if len(candidates) == 0 {
+		return false
+	}
+	// As much as the candidates have in common
+	common := []rune(candidates[0])
+	for _, candidate := range candidates[1:] {
+		rc := []rune(candidate)
+		n := 0
+		for n < len(common) && n < len(rc) && common[n] == rc[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	if len(common) > e.index || string(common) != string(e.line[:e.index]) {
+		if e.opts.MaxLength > 0 && len(e.line)-e.index+len(common) > e.opts.MaxLength {
+			return false
+		}
+		return e.edit(splice(e.line, 0, e.index, common...), 0, len(common))
+	}
+	if len(candidates) == 1 || !e.opts.ShowCandidates {
+		return len(candidates) == 1
+	}
+	e.showCandidates(candidates)
+	return true
+}
+
+// showCandidates lists candidates on the rows below the line, scrolling up to make
+// room if it has to, and as many as will fit with the line still showing.
+func (e *lineEditor) showCandidates(candidates []string) {
+	c := e.c
+	h, w := c.getScrollingAreaSize()
+	// Lay them out in rows, two spaces apart
+	rows := [][]rune{{}}
+	for _, candidate := range candidates {
+		rc := []rune(candidate)
+		if len(rc) > w {
+			rc = rc[:w]
+		}
+		row := rows[len(rows)-1]
+		if len(row) > 0 && len(row)+2+len(rc) > w {
+			rows = append(rows, []rune{})
+			row = nil
+		}
+		if len(row) > 0 {
+			row = append(row, ' ', ' ')
+		}
+		rows[len(rows)-1] = append(row, rc...)
+	}
+	// Scroll up if there's no room below the line, but not so far the cursor goes
+	endRow, _ := e.position(len(e.line))
+	cursorRow, _ := e.position(e.index)
+	most := h - endRow + cursorRow - 1
+	if most <= 0 {
+		// The line fills the scrolling area from the cursor down, so there's no room
+		return
+	}
+	if len(rows) > most {
+		rows = rows[:most]
+	}
+	if n := len(rows) - (h - endRow); n > 0 {
+		c.scrollUp(n)
+		e.start[0] -= n
+		endRow -= n
+	}
+	// Remember what's underneath and put the list on top
+	e.candidateRow = endRow + 1
+	for i, row := range rows {
+		saved := make([]Cell, w)
+		for col := range saved {
+			saved[col] = c.getCell(e.candidateRow+i, col+1)
+		}
+		e.candidates = append(e.candidates, saved)
+		c.eraseArea(e.candidateRow+i, 1, e.candidateRow+i, w)
+		for col, r := range row {
+			c.curpos = [2]int{e.candidateRow + i, col + 1}
+			c.plonk(r)
+		}
+	}
+	row, col := e.position(e.index)
+	c.curpos = [2]int{row, col}
+}
+
+// hideCandidates puts back what was underneath the candidates list.
+func (e *lineEditor) hideCandidates() {
+	for i, saved := range e.candidates {
+		for col, cell := range saved {
+			e.c.drawCell(e.candidateRow+i, col+1, cell)
+		}
+	}
+	e.candidates = nil
+}
+
+// moveTo moves the cursor to i in the line, or returns false if that's outside it.
+func (e *lineEditor) moveTo(i int) bool {
+	if i < 0 || i > len(e.line) {
+		return false
+	}
+	e.update(len(e.line), i)
+	return true
+}
+
+// recall replaces the line with line i of the history, or the new line if i is just
+// past the end, or returns false if there's no such line.
+func (e *lineEditor) recall(i int) bool {
+	h := e.opts.History
+	if h == nil || i < 0 || i > len(h.lines) {
+		return false
+	}
+	if e.historyIndex == len(h.lines) {
+		e.draft = e.line
+	}
+	e.historyIndex = i
+	if i == len(h.lines) {
+		e.line = e.draft
+	} else {
+		e.line = []rune(h.lines[i])
+	}
+	e.scrollTo(0)
+	e.update(0, len(e.line))
+	return true
+}
+
+// update redraws the line from changed onwards and moves the cursor to index, scrolling
+// if the end of the line or the cursor would be outside the scrolling area.
+func (e *lineEditor) update(changed, index int) {
+	e.index = index
+	scrolledEnd := e.scrollTo(len(e.line))
+	if e.scrollTo(index) || scrolledEnd {
+		changed = 0
+	}
+	e.draw(changed)
+	if row, col := e.position(index); row >= 1 {
+		e.c.curpos = [2]int{row, col}
+	}
+}
+
+// position returns the curpos of i in the line.  The row is < 1 if it's scrolled off
+// the top and > the height of the scrolling area if it's off the bottom.
+func (e *lineEditor) position(i int) (row, col int) {
+	_, w := e.c.getScrollingAreaSize()
+	offset := e.start[1] - 1 + i
+	return e.start[0] + offset/w, offset%w + 1
+}
+
+// scrollTo scrolls the scrolling area so that i in the line is in it, and returns true
+// if it had to.
+func (e *lineEditor) scrollTo(i int) bool {
+	h, _ := e.c.getScrollingAreaSize()
+	row, _ := e.position(i)
+	switch {
+	case row > h:
+		e.c.scrollUp(row - h)
+		e.start[0] -= row - h
+	case row < 1:
+		e.c.scrollDown(1 - row)
+		e.start[0] += 1 - row
+	default:
+		return false
+	}
+	return true
+}
+
+// draw plots the line from i onwards, as much of it as is in the scrolling area, and
+// erases whatever's left of it from before.
+func (e *lineEditor) draw(i int) {
+	c := e.c
+	h, w := c.getScrollingAreaSize()
+	if i == 0 && e.start[0] >= 1 {
+		for col, cell := range e.prefix {
+			c.drawCell(e.start[0], col+1, cell)
+		}
+	}
+	for j := i; j < len(e.line); j++ {
+		row, col := e.position(j)
+		if row < 1 {
+			continue
+		}
+		if row > h {
+			break
+		}
+		r := e.line[j]
+		// Control char
+		if r < 32 && !c.printControlChars {
+			r = 0
+		}
+		c.curpos = [2]int{row, col}
+		c.plonk(r)
+	}
+	// Erase the rest a row at a time
+	for j := len(e.line); j < e.drawn; {
+		row, col := e.position(j)
+		n := w - col + 1
+		if n > e.drawn-j {
+			n = e.drawn - j
+		}
+		if row >= 1 && row <= h {
+			c.eraseArea(row, col, row, col+n-1)
+		}
+		j += n
+	}
+	e.drawn = len(e.line)
+}
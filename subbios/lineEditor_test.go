@@ -0,0 +1,195 @@
+package subbios
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+)
+
+// scan edits line with keys then ENTER and returns the result.
+func scan(sio *Stdio, opts ScanfOptions, line, keys string) string {
+	for _, r := range keys + "\n" {
+		sio.c.stdinBuffer.Enqueue(r)
+	}
+	buf := []rune(line)
+	sio.ScanfWithOptions(&buf, opts)
+	return string(buf)
+}
+
+func TestLineEditor(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	sio := &s.Stdio
+	const (
+		left      = string(KeyCodeLeft)
+		home      = string(KeyCodeHome)
+		end       = string(KeyCodeEnd)
+		del       = string(KeyCodeDelete)
+		ins       = string(KeyCodeInsert)
+		up        = string(KeyCodeUp)
+		down      = string(KeyCodeDown)
+		wordLeft  = string(keyCodeWordLeft)
+		wordRight = string(keyCodeWordRight)
+	)
+	history := NewHistory(2)
+	digits := func(r rune) bool { return unicode.IsDigit(r) }
+	tests := []struct {
+		opts ScanfOptions
+		line string
+		keys string
+		want string
+	}{
+		{ScanfOptions{}, "", "helo" + left + "l", "hello"},
+		{ScanfOptions{}, "world", home + "hello " + end + "!", "hello world!"},
+		{ScanfOptions{}, "abcdef", home + del + del + left + "x", "xcdef"},
+		{ScanfOptions{}, "one two three", wordLeft + wordLeft + "X" + wordRight + "Y", "one Xtwo Ythree"},
+		{ScanfOptions{}, "cat", home + ins + "b" + ins + "o", "boat"},
+		{ScanfOptions{MaxLength: 3, Filter: digits}, "", "1a2b34", "123"},
+		{ScanfOptions{History: history}, "", "first", "first"},
+		{ScanfOptions{History: history}, "", "second", "second"},
+		{ScanfOptions{History: history}, "", "third", "third"},
+		{ScanfOptions{History: history}, "", up + up + "!", "second!"},
+		{ScanfOptions{History: history}, "", "new" + up + up + up + down + down + down, "new"},
+	}
+	for _, test := range tests {
+		sio.Printf("\x1b[2J")
+		if got := scan(sio, test.opts, test.line, test.keys); got != test.want {
+			t.Errorf("editing %q with %q gave %q, want %q", test.line, test.keys, got, test.want)
+		}
+		if got := sio.GetScreenText()[0]; got != test.want {
+			t.Errorf("editing %q with %q left %q on the screen, want %q", test.line, test.keys, got, test.want)
+		}
+	}
+	if got := history.Lines(); len(got) != 2 || got[0] != "second!" || got[1] != "new" {
+		t.Errorf("history is %q, want the last 2 lines", got)
+	}
+	none := NewHistory(-1)
+	none.Add("x")
+	if got := none.Lines(); len(got) != 0 {
+		t.Errorf("history of size -1 is %q, want nothing", got)
+	}
+}
+
+func TestLineEditorWrap(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	sio := &s.Stdio
+	// A 10x2 scrolling area with the prompt on the bottom row, so the line won't fit
+	sio.Printf("\x1b[2J\x1b[1;1;2;10~B\x1b[2;1H> ")
+	keys := "abcdefghijklmnopqrstuvwxyz" + string(KeyCodeHome) + "*"
+	if got := scan(sio, ScanfOptions{}, "", keys); got != "*abcdefghijklmnopqrstuvwxyz" {
+		t.Fatalf("got %q", got)
+	}
+	// HOME scrolled the prompt back down and ENTER scrolled up to the end again
+	got := sio.GetScreenText()
+	for i, want := range []string{"hijklmnopq", "rstuvwxyz"} {
+		if got[i] != want {
+			t.Errorf("row %d is %q, want %q", i+1, got[i], want)
+		}
+	}
+	if row, col := sio.GetCurpos(); row != 2 || col != 10 {
+		t.Errorf("cursor at %d, %d after ENTER, want 2, 10", row, col)
+	}
+	lines := sio.GetScrollback()
+	top := ""
+	for _, cell := range lines[len(lines)-1] {
+		top += string(rune(cell.Char))
+	}
+	if top != "> *abcdefg" {
+		t.Errorf("top of the line scrolled off as %q, want %q", top, "> *abcdefg")
+	}
+}
+
+func TestLineEditorCompletion(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	sio := &s.Stdio
+	commands := []string{"list", "load", "save"}
+	complete := func(line string) []string {
+		candidates := []string{}
+		for _, command := range commands {
+			if strings.HasPrefix(command, line) {
+				candidates = append(candidates, command+" ")
+			}
+		}
+		return candidates
+	}
+	max255 := func(line string) bool {
+		n, err := strconv.Atoi(line)
+		return line == "" || (err == nil && n <= 255)
+	}
+	tests := []struct {
+		opts ScanfOptions
+		keys string
+		want string
+	}{
+		{ScanfOptions{Completer: complete}, "s\tfile", "save file"},
+		{ScanfOptions{Completer: complete}, "x\t", "x"},
+		{ScanfOptions{Completer: complete}, "l\to\t", "load "},
+		{ScanfOptions{Validator: max255}, "25x5", "255"},
+		{ScanfOptions{Validator: max255}, "2x\b5", "5"},
+		{ScanfOptions{Validator: max255}, "256", "25"},
+	}
+	for _, test := range tests {
+		sio.Printf("\x1b[2J")
+		if got := scan(sio, test.opts, "", test.keys); got != test.want {
+			t.Errorf("typing %q gave %q, want %q", test.keys, got, test.want)
+		}
+	}
+
+	// TAB lists the candidates below the line, and they go away again
+	sio.Printf("\x1b[2J\x1b[1;1;3;20~B\x1b[3;1Hkeep me\r\n\x1b[3;1H> ")
+	opts := ScanfOptions{Completer: complete, ShowCandidates: true}
+	for _, r := range "l\t" {
+		sio.c.stdinBuffer.Enqueue(r)
+	}
+	// The cursor's only read by the editor, so the filter notes where it was when
+	// the next key came, and that's checked once ScanfWithOptions has returned
+	var row, col int
+	opts.Filter = func(r rune) bool {
+		row, col = sio.GetCurpos()
+		return true
+	}
+	done := make(chan string)
+	go func() {
+		buf := []rune{}
+		sio.ScanfWithOptions(&buf, opts)
+		done <- string(buf)
+	}()
+	want := []string{"keep me", "> l", "list   load"}
+	for !reflect.DeepEqual(sio.GetScreenText()[:3], want) {
+		select {
+		case <-done:
+			t.Fatalf("ScanfWithOptions returned early")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	sio.c.stdinBuffer.Enqueue('i')
+	sio.c.stdinBuffer.Enqueue('\n')
+	if got := <-done; got != "li" {
+		t.Errorf("got %q, want %q", got, "li")
+	}
+	if row != 2 || col != 4 {
+		t.Errorf("cursor at %d, %d with the list showing, want 2, 4", row, col)
+	}
+	if got := sio.GetScreenText()[:3]; !reflect.DeepEqual(got, []string{"keep me", "> li", ""}) {
+		t.Errorf("screen shows %q after the list", got)
+	}
+
+	// There's no room for the list if the line fills the scrolling area below the cursor
+	sio.Printf("\x1b[2J\x1b[1;1;3;10~B")
+	line := strings.Repeat("x", 40)
+	opts = ScanfOptions{Completer: complete, ShowCandidates: true}
+	if got := scan(sio, opts, line, string(KeyCodeHome)+"\t"); got != line {
+		t.Errorf("TAB at the start of a long line gave %q, want %q", got, line)
+	}
+	if got := sio.GetScreenText()[:3]; !reflect.DeepEqual(got, []string{"xxxxxxxxxx", "xxxxxxxxxx", ""}) {
+		t.Errorf("screen shows %q after TAB at the start of a long line", got)
+	}
+}
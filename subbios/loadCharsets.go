@@ -3,6 +3,7 @@ package subbios
 import (
 	"bytes"
 	"image"
+	_ "image/png" // import only for side-effects
 	"log"
 
 	"github.com/adamstimb/nimgobus/internal/make2darray"
@@ -0,0 +1,404 @@
+package subbios
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Envelopes that can be selected with S in a PLAY string.
+const (
+	envelopeNone    = 0 // Flat
+	envelopeDecay   = 1 // Starts loud and fades away
+	envelopePiano   = 2 // Quick attack then a long decay
+	envelopeSwell   = 3 // Fades in
+	envelopeTremolo = 4 // Wobbles
+)
+
+// musicNote is a note or rest in a tune, with everything the synthesiser needs to
+// know to play it.
+type musicNote struct {
+	frequency float64 // In Hz, or 0 for a rest
+	noise     bool    // Noise rather than a square wave
+	volume    int     // 0-15
+	envelope  int     // One of the envelope constants
+	sound     int     // Number of samples the note sounds for
+	length    int     // Number of samples until the next note, including the gap after the sound
+}
+
+// musicState holds the settings that carry on from one note to the next while a
+// PLAY string is parsed.
+type musicState struct {
+	octave       int     // 0-6
+	length       int     // Default note length, 1-64 (4 is a crotchet)
+	tempo        int     // Crotchets per minute, 32-255
+	articulation float64 // Fraction of each note that sounds
+	volume       int     // 0-15
+	envelope     int     // 0-4
+	noise        bool    // W1 selects noise
+}
+
+// newMusicState returns the settings at the start of a PLAY string.
+func newMusicState() musicState {
+	return musicState{octave: 4, length: 4, tempo: 120, articulation: 7.0 / 8.0, volume: 15}
+}
+
+// noteSemitones is the number of semitones above C of each note letter.
+var noteSemitones = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+// musicParser turns a PLAY string into notes.
+type musicParser struct {
+	mml   string
+	pos   int
+	state musicState
+}
+
+// parseMusic parses a PLAY string.  The language is the one from BASIC, plus S for
+// the shape of the envelope and W for the waveform:
+// A-G - play a note, followed by # or + for sharp or - for flat, then an optional
+// length and any number of dots, each of which makes the note half as long again.
+// N n - play note n (1-84, 0 is a rest) where 37 is middle C.
+// O n - set the octave (0-6, default 4).  Octave 3 starts at middle C.
+// > and < - go up or down an octave.
+// L n - set the default length of notes (1-64, default 4).  1 is a semibreve, 4 a
+// crotchet and so on.
+// P n or R n - rest for length n, which can be followed by dots too.
+// T n - set the tempo in crotchets per minute (32-255, default 120).
+// MN, ML, MS - normal (7/8 of each note sounds), legato or staccato (3/4).
+// MF, MB - foreground or background; accepted for compatibility but PLAY always
+// plays in the background.
+// V n - set the volume (0-15, default 15).
+// S n - select an envelope: 0 flat (default), 1 decay, 2 piano, 3 swell, 4 tremolo.
+// W n - select the waveform: 0 square (default), 1 noise.
+// Spaces and semicolons are ignored and letters can be upper or lower case.
+func parseMusic(mml string) ([]musicNote, error) {
+	p := &musicParser{mml: strings.ToUpper(mml), state: newMusicState()}
+	notes := []musicNote{}
+	for p.pos < len(p.mml) {
+		start := p.pos
+		c := p.mml[p.pos]
+		p.pos++
+		switch {
+		case c == ' ' || c == ';':
+			continue
+		case c >= 'A' && c <= 'G':
+			semitone := noteSemitones[c]
+			if p.pos < len(p.mml) {
+				switch p.mml[p.pos] {
+				case '#', '+':
+					semitone++
+					p.pos++
+				case '-':
+					semitone--
+					p.pos++
+				}
+			}
+			length, ok := p.number()
+			if !ok {
+				length = p.state.length
+			}
+			if length < 1 || length > 64 {
+				return nil, p.error(start)
+			}
+			// Don't let C flat and B sharp fall off the ends
+			n := p.state.octave*12 + semitone + 1
+			if n < 1 {
+				n = 1
+			}
+			if n > 84 {
+				n = 84
+			}
+			notes = append(notes, p.note(n, length, p.dots()))
+		case c == 'N':
+			n, ok := p.number()
+			if !ok || n < 0 || n > 84 {
+				return nil, p.error(start)
+			}
+			notes = append(notes, p.note(n, p.state.length, p.dots()))
+		case c == 'P' || c == 'R':
+			length, ok := p.number()
+			if !ok || length < 1 || length > 64 {
+				return nil, p.error(start)
+			}
+			notes = append(notes, p.note(0, length, p.dots()))
+		case c == '>':
+			if p.state.octave < 6 {
+				p.state.octave++
+			}
+		case c == '<':
+			if p.state.octave > 0 {
+				p.state.octave--
+			}
+		case c == 'M':
+			if p.pos >= len(p.mml) {
+				return nil, p.error(start)
+			}
+			// Past the letter first so a bad one is in the error
+			p.pos++
+			switch p.mml[p.pos-1] {
+			case 'N':
+				p.state.articulation = 7.0 / 8.0
+			case 'L':
+				p.state.articulation = 1
+			case 'S':
+				p.state.articulation = 3.0 / 4.0
+			case 'F', 'B':
+			default:
+				return nil, p.error(start)
+			}
+		case c == 'O':
+			if err := p.setting(start, 0, 6, &p.state.octave); err != nil {
+				return nil, err
+			}
+		case c == 'L':
+			if err := p.setting(start, 1, 64, &p.state.length); err != nil {
+				return nil, err
+			}
+		case c == 'T':
+			if err := p.setting(start, 32, 255, &p.state.tempo); err != nil {
+				return nil, err
+			}
+		case c == 'V':
+			if err := p.setting(start, 0, 15, &p.state.volume); err != nil {
+				return nil, err
+			}
+		case c == 'S':
+			if err := p.setting(start, 0, 4, &p.state.envelope); err != nil {
+				return nil, err
+			}
+		case c == 'W':
+			waveform := 0
+			if err := p.setting(start, 0, 1, &waveform); err != nil {
+				return nil, err
+			}
+			p.state.noise = waveform == 1
+		default:
+			return nil, p.error(start)
+		}
+	}
+	return notes, nil
+}
+
+// number reads a number if there is one.
+func (p *musicParser) number() (n int, ok bool) {
+	for p.pos < len(p.mml) && p.mml[p.pos] >= '0' && p.mml[p.pos] <= '9' {
+		n = n*10 + int(p.mml[p.pos]-'0')
+		ok = true
+		p.pos++
+		if n > 9999 {
+			return n, ok
+		}
+	}
+	return n, ok
+}
+
+// setting reads the number after a command that changes a setting and stores it in
+// value if it's between min and max.
+func (p *musicParser) setting(start, min, max int, value *int) error {
+	n, ok := p.number()
+	if !ok || n < min || n > max {
+		return p.error(start)
+	}
+	*value = n
+	return nil
+}
+
+// dots reads any dots after a note and returns how many there were.
+func (p *musicParser) dots() int {
+	n := 0
+	for p.pos < len(p.mml) && p.mml[p.pos] == '.' {
+		n++
+		p.pos++
+	}
+	return n
+}
+
+// note returns note number n (0 for a rest) of the given length with the current
+// settings.
+func (p *musicParser) note(n, length, dots int) musicNote {
+	seconds := 240.0 / float64(p.state.tempo) / float64(length)
+	for i, extra := 0, seconds/2; i < dots; i, extra = i+1, extra/2 {
+		seconds += extra
+	}
+	samples := int(seconds * SampleRate)
+	note := musicNote{
+		noise:    p.state.noise,
+		volume:   p.state.volume,
+		envelope: p.state.envelope,
+		sound:    int(float64(samples) * p.state.articulation),
+		length:   samples,
+	}
+	if n > 0 {
+		// Note 46 is the A above middle C
+		note.frequency = 440 * math.Pow(2, float64(n-46)/12)
+	} else {
+		note.sound = 0
+	}
+	return note
+}
+
+// error returns an error pointing at the command starting at start.
+func (p *musicParser) error(start int) error {
+	end := p.pos
+	if end <= start {
+		end = start + 1
+	}
+	if end > len(p.mml) {
+		end = len(p.mml)
+	}
+	return invalidParameter("Play", "mml", fmt.Sprintf("%q at %d", p.mml[start:end], start))
+}
+
+// music is the little synthesiser that plays tunes in the background.  It's mixed
+// into the sound chip's stream, so it moves on as the stream is read.
+type music struct {
+	mu          sync.Mutex
+	queue       []musicNote
+	position    int     // Samples into the current note
+	phase       float64 // 0-1 through the current cycle of the square wave
+	noiseLFSR   uint32
+	noiseOutput bool
+	beepLeft    int     // Samples of beep still to play
+	beepPhase   float64 // 0-1 through the current cycle of the beep
+}
+
+// The beep for BEL is a short high square wave.
+const (
+	beepFrequency = 1000
+	beepLength    = SampleRate / 8
+)
+
+// newMusic returns a music with nothing to play.
+func newMusic() *music {
+	return &music{noiseLFSR: 1}
+}
+
+// sample returns the next sample of whatever's playing, -1 to 1, and moves on.
+func (m *music) sample() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.beepSample() + m.noteSample()
+}
+
+// beepSample returns the next sample of the BEL beep, if it's beeping.
+func (m *music) beepSample() float64 {
+	if m.beepLeft == 0 {
+		return 0
+	}
+	m.beepLeft--
+	m.beepPhase += float64(beepFrequency) / SampleRate
+	if m.beepPhase >= 1 {
+		m.beepPhase--
+	}
+	if m.beepPhase >= 0.5 {
+		return -0.5
+	}
+	return 0.5
+}
+
+// noteSample returns the next sample of the tune from FPlay, if there is one.
+func (m *music) noteSample() float64 {
+	if len(m.queue) == 0 {
+		return 0
+	}
+	n := &m.queue[0]
+	level := 0.0
+	if n.frequency > 0 && m.position < n.sound {
+		level = psgVolumes[n.volume] * envelopeLevel(n.envelope, m.position, n.sound)
+		step := n.frequency / SampleRate
+		if n.noise {
+			// Noise changes at twice the note frequency so higher notes hiss more
+			for m.phase += step * 2; m.phase >= 1; m.phase-- {
+				bit := (m.noiseLFSR ^ (m.noiseLFSR >> 3)) & 1
+				m.noiseLFSR = (m.noiseLFSR >> 1) | (bit << 16)
+				m.noiseOutput = m.noiseLFSR&1 != 0
+			}
+			if !m.noiseOutput {
+				level = -level
+			}
+		} else {
+			m.phase += step
+			if m.phase >= 1 {
+				m.phase -= math.Floor(m.phase)
+			}
+			if m.phase >= 0.5 {
+				level = -level
+			}
+		}
+	}
+	m.position++
+	if m.position >= n.length {
+		m.queue = m.queue[1:]
+		m.position = 0
+	}
+	return level
+}
+
+// envelopeLevel returns the level, 0-1, of an envelope at position samples into a
+// note that sounds for length samples.
+func envelopeLevel(envelope, position, length int) float64 {
+	t := float64(position) / float64(length)
+	switch envelope {
+	case envelopeDecay:
+		return 1 - t
+	case envelopePiano:
+		attack := 0.02 * SampleRate
+		if float64(position) < attack {
+			return float64(position) / attack
+		}
+		return math.Exp(-3 * (float64(position) - attack) / SampleRate)
+	case envelopeSwell:
+		return t
+	case envelopeTremolo:
+		return 0.75 + 0.25*math.Sin(2*math.Pi*6*float64(position)/SampleRate)
+	}
+	return 1
+}
+
+// beep starts the BEL beep, or starts it again if it's already beeping.
+func (m *music) beep() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.beepLeft = beepLength
+}
+
+// add queues up notes to play after anything that's already playing.
+func (m *music) add(notes []musicNote) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queue = append(m.queue, notes...)
+}
+
+// stop throws away everything that's queued up.
+func (m *music) stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queue = nil
+	m.position = 0
+}
+
+// skip moves the tune on by n samples without playing them, for when nothing is
+// reading the stream.
+func (m *music) skip(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for n > 0 && len(m.queue) > 0 {
+		left := m.queue[0].length - m.position
+		if n < left {
+			m.position += n
+			return
+		}
+		n -= left
+		m.queue = m.queue[1:]
+		m.position = 0
+	}
+}
+
+// notesLeft returns the number of notes and rests still to play, including the one
+// playing now.
+func (m *music) notesLeft() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.queue)
+}
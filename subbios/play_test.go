@@ -0,0 +1,115 @@
+package subbios
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMusic(t *testing.T) {
+	notes, err := parseMusic("t120 l4 o3 a c8. p2 >c# ms n0 w1 s1 v7 b-16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		frequency     float64
+		sound, length int
+	}{
+		{440, SampleRate / 2 * 7 / 8, SampleRate / 2},            // Crotchet at 120 bpm is half a second
+		{261.63, SampleRate * 3 / 8 * 7 / 8, SampleRate * 3 / 8}, // Dotted quaver
+		{0, 0, SampleRate}, // Minim rest
+		{554.37, SampleRate / 2 * 7 / 8, SampleRate / 2}, // Sharp
+		{0, 0, SampleRate / 2},                           // N0 is a rest
+		{932.33, SampleRate / 8 * 3 / 4, SampleRate / 8}, // Staccato B flat
+	}
+	if len(notes) != len(want) {
+		t.Fatalf("got %d notes, want %d", len(notes), len(want))
+	}
+	for i, w := range want {
+		n := notes[i]
+		if math.Abs(n.frequency-w.frequency) > 0.01 || n.sound != w.sound || n.length != w.length {
+			t.Errorf("note %d = %.2f Hz for %d/%d samples, want %.2f Hz for %d/%d", i, n.frequency, n.sound, n.length, w.frequency, w.sound, w.length)
+		}
+	}
+	last := notes[len(notes)-1]
+	if !last.noise || last.envelope != envelopeDecay || last.volume != 7 {
+		t.Errorf("last note has noise=%v envelope=%d volume=%d", last.noise, last.envelope, last.volume)
+	}
+
+	for _, mml := range []string{"H", "S5", "O7", "L0", "T300", "MX", "P", "N85", "C65"} {
+		if _, err := parseMusic(mml); !errors.Is(err, ErrInvalidParameter) {
+			t.Errorf("parseMusic(%q) error = %v, want ErrInvalidParameter", mml, err)
+		}
+	}
+	if _, err := parseMusic("L4 MX"); err == nil || !strings.Contains(err.Error(), `"MX" at 3`) {
+		t.Errorf("parseMusic(\"L4 MX\") error = %v, want it to point at \"MX\" at 3", err)
+	}
+}
+
+func TestPlay(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	s.TSound.FPlay("T240 L4 CDEF")
+	if s.FunctionError != 0 {
+		t.Fatalf("FunctionError = %d", s.FunctionError)
+	}
+	if n := s.TSound.NotesLeft(); n != 4 {
+		t.Errorf("NotesLeft() = %d, want 4", n)
+	}
+	// Each note is a quarter of a second
+	got := samples(t, mustRender(t, &s, 600*time.Millisecond))
+	if n := s.TSound.NotesLeft(); n != 2 {
+		t.Errorf("NotesLeft() = %d after 0.6s, want 2", n)
+	}
+	loud := 0
+	for _, v := range got {
+		if v > 5000 || v < -5000 {
+			loud++
+		}
+	}
+	if loud == 0 {
+		t.Errorf("nothing was played")
+	}
+	s.TSound.StopPlaying()
+	if s.TSound.IsPlaying() {
+		t.Errorf("still playing after StopPlaying")
+	}
+	// CTRL+C stops it too
+	s.TSound.FPlay("CDEF")
+	s.TSound.checkKeyboardInterrupts(testInput{keys: map[Key]int{KeyControl: 10, KeyC: 10}})
+	if s.TSound.IsPlaying() {
+		t.Errorf("still playing after CTRL+C")
+	}
+	if !s.TSound.GetCtrlCInterrupt(true) || s.TSound.GetCtrlCInterrupt(false) {
+		t.Errorf("CTRL+C interrupt flag not set then unset")
+	}
+	// Unless it's suppressed
+	s.TSound.SuppressCtrlCInterrupt = true
+	s.TSound.FPlay("CDEF")
+	s.TSound.checkKeyboardInterrupts(testInput{keys: map[Key]int{KeyControl: 10, KeyC: 10}})
+	if !s.TSound.IsPlaying() {
+		t.Errorf("stopped playing after suppressed CTRL+C")
+	}
+}
+
+func TestPlayHeadless(t *testing.T) {
+	// Nothing reads the stream, so Update moves the tune on, and the same goes for a
+	// backend that couldn't start its sound
+	for _, b := range []Backend{NewImageBackend(nil), mutedBackend{NewImageBackend(nil)}} {
+		s := Subbios{}
+		s.InitWithBackend(b)
+		t.Cleanup(s.Close)
+		s.TSound.FPlay("T240 L16 CD")
+		deadline := time.Now().Add(5 * time.Second)
+		for s.TSound.IsPlaying() {
+			if time.Now().After(deadline) {
+				t.Fatalf("still playing after 5s with %d notes left (backend %T)", s.TSound.NotesLeft(), b)
+			}
+			s.Update()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package subbios
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"sync"
+)
+
+// SampleRate is the sample rate of the sound, in samples per second.  The stream
+// from TSound.Stream and WAVs from TSound.RenderWAV are 16-bit signed little-endian
+// stereo at this rate, which is what Ebiten audio likes.
+const SampleRate = 44100
+
+// psgClock is the clock frequency of the sound chip in Hz.  Tone frequency is
+// psgClock / (16 * period).
+const psgClock = 2000000
+
+// psgVolumes is the output level of the chip's logarithmic DAC for each volume.
+var psgVolumes = [16]float64{
+	0, 0.0137, 0.0205, 0.0291, 0.0423, 0.0618, 0.0847, 0.1369,
+	0.1691, 0.2647, 0.3527, 0.4499, 0.5704, 0.6873, 0.8482, 1.0,
+}
+
+// PSG register numbers.
+const (
+	psgToneFineA      = 0  // Tone period of voice A, low 8 bits (then B, C at 2 and 4)
+	psgToneCoarseA    = 1  // Tone period of voice A, high 4 bits (then B, C at 3 and 5)
+	psgNoisePeriod    = 6  // Noise period, 5 bits
+	psgMixer          = 7  // Bits 0-2 switch tone off for A-C, bits 3-5 switch noise off
+	psgAmplitudeA     = 8  // Volume of voice A (then B, C at 9 and 10), bit 4 uses the envelope
+	psgEnvelopeFine   = 11 // Envelope period, low 8 bits
+	psgEnvelopeCoarse = 12 // Envelope period, high 8 bits
+	psgEnvelopeShape  = 13 // Envelope shape, 4 bits: continue, attack, alternate, hold
+)
+
+// psg emulates the three-voice programmable sound generator, i.e. an AY-3-8910.
+// The chip is ticked at psgClock / 8 and each tick does half a cycle of the fastest
+// possible tone.
+type psg struct {
+	mu           sync.Mutex
+	regs         [16]uint8
+	tickFraction float64 // Ticks owed to the next sample
+	toneCounter  [3]int  // Counts up to the tone period
+	toneOutput   [3]bool // Square wave output of each voice
+	noiseCounter int     // Counts up to twice the noise period
+	noiseLFSR    uint32  // 17-bit shift register for the noise
+	envCounter   int     // Counts up to twice the envelope period
+	envStep      int     // 0-15 through the current envelope cycle
+	envAttack    bool    // Envelope going up rather than down
+	envHolding   bool    // Envelope has finished and stays where it is
+}
+
+// newPSG returns a psg with everything switched off.
+func newPSG() *psg {
+	p := &psg{}
+	p.reset()
+	return p
+}
+
+// reset puts the chip back the way it was at power on: silent with all tones and
+// noise disabled.
+func (p *psg) reset() {
+	p.regs = [16]uint8{}
+	p.regs[psgMixer] = 0x3f
+	p.tickFraction = 0
+	p.toneCounter = [3]int{}
+	p.toneOutput = [3]bool{}
+	p.noiseCounter = 0
+	p.noiseLFSR = 1
+	p.envCounter = 0
+	p.envStep = 0
+	p.envAttack = false
+	p.envHolding = true
+}
+
+// writeRegister sets a register.  Writing the envelope shape restarts the envelope.
+func (p *psg) writeRegister(register int, value uint8) {
+	switch register {
+	case psgToneCoarseA, psgToneCoarseA + 2, psgToneCoarseA + 4:
+		value &= 0x0f
+	case psgNoisePeriod:
+		value &= 0x1f
+	case psgAmplitudeA, psgAmplitudeA + 1, psgAmplitudeA + 2:
+		value &= 0x1f
+	case psgEnvelopeShape:
+		value &= 0x0f
+		p.envStep = 0
+		p.envCounter = 0
+		p.envAttack = value&4 != 0
+		p.envHolding = false
+	}
+	p.regs[register] = value
+}
+
+// tonePeriod returns the tone period of a voice, treating 0 as 1 like the chip does.
+func (p *psg) tonePeriod(voice int) int {
+	period := int(p.regs[psgToneFineA+voice*2]) | int(p.regs[psgToneCoarseA+voice*2])<<8
+	if period == 0 {
+		period = 1
+	}
+	return period
+}
+
+// tick advances the chip by one tick of psgClock / 8.
+func (p *psg) tick() {
+	// Tones
+	for voice := 0; voice < 3; voice++ {
+		p.toneCounter[voice]++
+		if p.toneCounter[voice] >= p.tonePeriod(voice) {
+			p.toneCounter[voice] = 0
+			p.toneOutput[voice] = !p.toneOutput[voice]
+		}
+	}
+	// Noise
+	noisePeriod := int(p.regs[psgNoisePeriod])
+	if noisePeriod == 0 {
+		noisePeriod = 1
+	}
+	p.noiseCounter++
+	if p.noiseCounter >= noisePeriod*2 {
+		p.noiseCounter = 0
+		bit := (p.noiseLFSR ^ (p.noiseLFSR >> 3)) & 1
+		p.noiseLFSR = (p.noiseLFSR >> 1) | (bit << 16)
+	}
+	// Envelope
+	if p.envHolding {
+		return
+	}
+	envPeriod := int(p.regs[psgEnvelopeFine]) | int(p.regs[psgEnvelopeCoarse])<<8
+	if envPeriod == 0 {
+		envPeriod = 1
+	}
+	p.envCounter++
+	if p.envCounter < envPeriod*2 {
+		return
+	}
+	p.envCounter = 0
+	p.envStep++
+	if p.envStep <= 15 {
+		return
+	}
+	// End of a cycle, so what happens next depends on the shape
+	shape := p.regs[psgEnvelopeShape]
+	switch {
+	case shape&8 == 0: // Don't continue: drop to 0 and stay there
+		p.envHolding = true
+		p.envAttack = false
+		p.envStep = 15
+	case shape&1 != 0: // Hold, maybe at the other end
+		p.envHolding = true
+		if shape&2 != 0 {
+			p.envAttack = !p.envAttack
+		}
+		p.envStep = 15
+	default: // Go round again, maybe in the other direction
+		if shape&2 != 0 {
+			p.envAttack = !p.envAttack
+		}
+		p.envStep = 0
+	}
+}
+
+// envelopeVolume returns the current volume of the envelope, 0-15.
+func (p *psg) envelopeVolume() int {
+	if p.envAttack {
+		return p.envStep
+	}
+	return 15 - p.envStep
+}
+
+// sample advances the chip by one sample's worth of ticks and returns the mixed
+// output of the three voices.
+func (p *psg) sample() int16 {
+	p.tickFraction += float64(psgClock) / 8 / SampleRate
+	// Average the output over the ticks so high notes don't alias too horribly
+	sum := 0.0
+	n := 0
+	for ; p.tickFraction >= 1; p.tickFraction-- {
+		p.tick()
+		sum += p.output()
+		n++
+	}
+	if n == 0 {
+		sum = p.output()
+		n = 1
+	}
+	return int16(sum / float64(n) * 32767)
+}
+
+// output returns the mixed level of the three voices right now, 0 to 1.
+func (p *psg) output() float64 {
+	mixer := p.regs[psgMixer]
+	noise := p.noiseLFSR&1 != 0
+	level := 0.0
+	for voice := 0; voice < 3; voice++ {
+		toneOff := mixer&(1<<voice) != 0
+		noiseOff := mixer&(8<<voice) != 0
+		if !(p.toneOutput[voice] || toneOff) || !(noise || noiseOff) {
+			continue
+		}
+		amplitude := p.regs[psgAmplitudeA+voice]
+		volume := int(amplitude & 0x0f)
+		if amplitude&0x10 != 0 {
+			volume = p.envelopeVolume()
+		}
+		level += psgVolumes[volume]
+	}
+	return level / 3
+}
+
+// soundStream is an endless io.Reader of the sound chip's output with any music
+// from PLAY mixed in.
+type soundStream struct {
+	p *psg
+	m *music
+}
+
+// Read implements io.Reader.  It fills buf with 16-bit stereo samples, as many as
+// will fit, and never runs out.
+func (s soundStream) Read(buf []byte) (int, error) {
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+	n := len(buf) / 4 * 4
+	for i := 0; i < n; i += 4 {
+		// The music is about as loud as one voice of the chip
+		level := float64(s.p.sample()) + s.m.sample()*32767/3
+		level = math.Max(math.Min(level, 32767), -32768)
+		v := uint16(int16(level))
+		buf[i] = byte(v)
+		buf[i+1] = byte(v >> 8)
+		buf[i+2] = byte(v)
+		buf[i+3] = byte(v >> 8)
+	}
+	return n, nil
+}
+
+// writeWAV writes 16-bit stereo PCM samples at SampleRate to w as a WAV file.
+func writeWAV(w io.Writer, pcm []byte) error {
+	header := struct {
+		RIFF          [4]byte
+		Size          uint32
+		WAVE          [4]byte
+		Fmt           [4]byte
+		FmtSize       uint32
+		Format        uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		RIFF:          [4]byte{'R', 'I', 'F', 'F'},
+		Size:          uint32(36 + len(pcm)),
+		WAVE:          [4]byte{'W', 'A', 'V', 'E'},
+		Fmt:           [4]byte{'f', 'm', 't', ' '},
+		FmtSize:       16,
+		Format:        1, // PCM
+		Channels:      2,
+		SampleRate:    SampleRate,
+		ByteRate:      SampleRate * 4,
+		BlockAlign:    4,
+		BitsPerSample: 16,
+		Data:          [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      uint32(len(pcm)),
+	}
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	buf.Write(pcm)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package subbios
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/adamstimb/nimgobus/subbios/errorcode"
+)
+
+// openPTY isn't implemented here, so RunTerminal fails with EFuncNotImplemented.
+func openPTY() (ptmx, tty *os.File, err error) {
+	return nil, nil, newError("RunTerminal", errorcode.EFuncNotImplemented)
+}
+
+func setWinsize(ptmx *os.File, rows, cols int) error {
+	return newError("RunTerminal", errorcode.EFuncNotImplemented)
+}
+
+func startOnPTY(cmd *exec.Cmd, tty *os.File) error {
+	return newError("RunTerminal", errorcode.EFuncNotImplemented)
+}
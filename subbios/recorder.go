@@ -0,0 +1,203 @@
+package subbios
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/adamstimb/nimgobus/subbios/colour"
+	"github.com/adamstimb/nimgobus/subbios/errorcode"
+)
+
+// RecordingOptions controls how StartRecording records the screen.
+type RecordingOptions struct {
+	Rate        int           // Frames per second (1-50, default 10)
+	MaxDuration time.Duration // Recording stops by itself after this long (default 1 minute)
+	Border      bool          // Record the whole monitor, otherwise just the screen
+}
+
+// recording holds the frames captured so far.  Frames that are the same as the
+// previous one aren't stored, the previous frame's delay just gets longer.
+type recording struct {
+	mu          sync.Mutex
+	opts        RecordingOptions
+	now         func() time.Time // So tests can fake the passing of time
+	start       time.Time        // When recording started
+	lastCapture time.Time        // When the last frame was captured
+	lastChange  time.Time        // When the last stored frame was captured
+	stopped     bool             // Set to true when MaxDuration is reached
+	frames      []*image.Paletted
+	delays      []int // In 100ths of a second, as GIF likes it
+}
+
+// nimbusPalette is the 16 physical colours, which is all a Nimbus screen can show.
+var nimbusPalette = func() color.Palette {
+	p := color.Palette{}
+	for _, c := range colour.PhysicalColours {
+		p = append(p, c)
+	}
+	return p
+}()
+
+// StartRecording starts recording the rendered screen into an animated GIF, including
+// flashing colours and the cursor.  Frames are captured when the screen is rendered,
+// i.e. on each Update, so with a headless backend you need to keep calling Update.
+// Returns EAlreadyOn if a recording is already in progress.
+func (t *TGraphicsOutput) StartRecording(opts RecordingOptions) error {
+	return t.startRecording(opts, time.Now)
+}
+
+// startRecording is StartRecording with a clock.
+func (t *TGraphicsOutput) startRecording(opts RecordingOptions, now func() time.Time) error {
+	if opts.Rate == 0 {
+		opts.Rate = 10
+	}
+	if opts.Rate < 1 || opts.Rate > 50 {
+		return invalidParameter("StartRecording", "opts.Rate", opts.Rate)
+	}
+	if opts.MaxDuration == 0 {
+		opts.MaxDuration = time.Minute
+	}
+	if opts.MaxDuration < 0 {
+		return invalidParameter("StartRecording", "opts.MaxDuration", opts.MaxDuration)
+	}
+	t.v.muRecording.Lock()
+	defer t.v.muRecording.Unlock()
+	if t.v.recording != nil {
+		return newError("StartRecording", errorcode.EAlreadyOn)
+	}
+	t.v.recording = &recording{opts: opts, now: now, start: now()}
+	return nil
+}
+
+// IsRecording returns true if a recording is in progress and hasn't reached its
+// maximum duration.
+func (t *TGraphicsOutput) IsRecording() bool {
+	t.v.muRecording.Lock()
+	r := t.v.recording
+	t.v.muRecording.Unlock()
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.stopped
+}
+
+// StopRecording stops recording and returns the animation.  It still works after
+// the recording has reached its maximum duration.  Returns ENotInitialized if
+// nothing was being recorded.
+func (t *TGraphicsOutput) StopRecording() (*gif.GIF, error) {
+	t.v.muRecording.Lock()
+	r := t.v.recording
+	t.v.recording = nil
+	t.v.muRecording.Unlock()
+	if r == nil {
+		return nil, newError("StopRecording", errorcode.ENotInitialized)
+	}
+	return r.gif(), nil
+}
+
+// SaveRecording stops recording and saves the animation to a GIF file.
+func (t *TGraphicsOutput) SaveRecording(path string) error {
+	g, err := t.StopRecording()
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(f, g); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// captureFrame adds the latest rendered image to the recording if it's time for
+// another frame.  It's called by renderMonitor.
+func (v *video) captureFrame() {
+	v.muRecording.Lock()
+	r := v.recording
+	v.muRecording.Unlock()
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return
+	}
+	now := r.now()
+	if now.Sub(r.start) >= r.opts.MaxDuration {
+		r.finish(r.start.Add(r.opts.MaxDuration))
+		r.stopped = true
+		return
+	}
+	if len(r.frames) > 0 && now.Sub(r.lastCapture) < time.Second/time.Duration(r.opts.Rate) {
+		return
+	}
+	r.lastCapture = now
+	src := v.screenImage
+	if r.opts.Border {
+		src = v.monitorImage
+	}
+	frame := toPaletted(src)
+	// Same as last time?  Then make do with the last frame.
+	if n := len(r.frames); n > 0 && bytes.Equal(r.frames[n-1].Pix, frame.Pix) {
+		return
+	}
+	r.finish(now)
+	r.frames = append(r.frames, frame)
+	r.delays = append(r.delays, 0)
+	r.lastChange = now
+}
+
+// finish sets the delay of the last frame so it lasts until end.
+func (r *recording) finish(end time.Time) {
+	if len(r.frames) == 0 {
+		return
+	}
+	delay := int(end.Sub(r.lastChange) / (10 * time.Millisecond))
+	if delay < 1 {
+		delay = 1
+	}
+	r.delays[len(r.delays)-1] = delay
+}
+
+// gif returns the recording as an animated GIF.
+func (r *recording) gif() *gif.GIF {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.stopped {
+		r.finish(r.now())
+	}
+	return &gif.GIF{
+		Image: append([]*image.Paletted{}, r.frames...),
+		Delay: append([]int{}, r.delays...),
+	}
+}
+
+// toPaletted converts a rendered image to the Nimbus palette.  Every colour in a
+// rendered image is one of the physical colours, so it's an exact match.
+func toPaletted(src *image.RGBA) *image.Paletted {
+	index := map[color.RGBA]uint8{}
+	for i, c := range colour.PhysicalColours {
+		index[c] = uint8(i)
+	}
+	b := src.Bounds()
+	img := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), nimbusPalette)
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			i := src.PixOffset(b.Min.X+x, b.Min.Y+y)
+			c := color.RGBA{src.Pix[i], src.Pix[i+1], src.Pix[i+2], src.Pix[i+3]}
+			img.Pix[y*img.Stride+x] = index[c]
+		}
+	}
+	return img
+}
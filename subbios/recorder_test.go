@@ -0,0 +1,60 @@
+package subbios
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/adamstimb/nimgobus/subbios/colour"
+)
+
+func TestRecording(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	s.Stdio.Printf("\x1b~F") // Hide cursor
+	clock := time.Unix(0, 0)
+	now := func() time.Time { return clock }
+	if err := s.TGraphicsOutput.startRecording(RecordingOptions{Rate: 10, MaxDuration: 2 * time.Second}, now); err != nil {
+		t.Fatalf("startRecording returned %v", err)
+	}
+	if err := s.TGraphicsOutput.StartRecording(RecordingOptions{}); !errors.Is(err, ErrAlreadyOn) {
+		t.Errorf("StartRecording while recording returned %v, expected %v", err, ErrAlreadyOn)
+	}
+	// 1 second of black, then half a second of blue, then nothing changes
+	for i := 0; i < 30; i++ {
+		if i == 10 {
+			s.TGraphicsOutput.FSetBorderColour(colour.DarkBlue)
+			s.Stdio.Printf("\x1b[2J")
+			s.TGraphicsOutput.FFillArea(1, 0, 1, 0, 0, []int{0, 0, 639, 0, 639, 249, 0, 249})
+		}
+		if i == 15 {
+			s.TGraphicsOutput.FFillArea(1, 0, 0, 0, 0, []int{0, 0, 639, 0, 639, 249, 0, 249})
+		}
+		s.Update()
+		clock = clock.Add(100 * time.Millisecond)
+	}
+	if s.TGraphicsOutput.IsRecording() {
+		t.Errorf("IsRecording returned true after MaxDuration")
+	}
+	g, err := s.TGraphicsOutput.StopRecording()
+	if err != nil {
+		t.Fatalf("StopRecording returned %v", err)
+	}
+	wantDelays := []int{100, 50, 50}
+	if len(g.Delay) != len(wantDelays) {
+		t.Fatalf("recording has delays %v, expected %v", g.Delay, wantDelays)
+	}
+	for i := range wantDelays {
+		if g.Delay[i] != wantDelays[i] {
+			t.Errorf("recording has delays %v, expected %v", g.Delay, wantDelays)
+			break
+		}
+	}
+	if b := g.Image[0].Bounds(); b.Dx() != 640 || b.Dy() != 500 {
+		t.Errorf("recording frames are %v, expected 640x500", b)
+	}
+	if _, err := s.TGraphicsOutput.StopRecording(); !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("StopRecording when not recording returned %v, expected %v", err, ErrNotInitialized)
+	}
+}
@@ -0,0 +1,107 @@
+package subbios
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+)
+
+// FlashPhase selects which physical colour a screenshot shows for flashing colours
+// in the colour lookup table.
+type FlashPhase int
+
+// Flash phases for screenshots.
+const (
+	FlashCurrent FlashPhase = iota // Whatever is on the screen right now
+	FlashFirst                     // Always the first physical colour
+	FlashSecond                    // Always the second physical colour
+)
+
+// ScreenshotOptions controls what Screenshot captures.
+type ScreenshotOptions struct {
+	Border bool       // Capture the whole monitor (MonitorWidth x MonitorHeight), otherwise just the screen at its own resolution
+	Flash  FlashPhase // Which phase of any flashing colours to show
+}
+
+// videoMemory flushes the draw queue and returns a copy of the video memory.
+func (v *video) videoMemory() [250][640]int {
+	v.flushDrawQueue()
+	v.muMemory.Lock()
+	defer v.muMemory.Unlock()
+	return v.memory
+}
+
+// screenSize returns the width and height of the screen in pixels for the current mode.
+func (v *video) screenSize() (width, height int) {
+	if v.screenWidth == 40 {
+		return 320, 250
+	}
+	return 640, 250
+}
+
+// Screenshot returns an image of what's on the screen with the current colour lookup
+// table applied.  Without a border the image is the screen at its own resolution,
+// i.e. 640x250 in 80-column mode and 320x250 in 40-column mode.  With a border it's
+// the monitor image, scaled up to the right aspect ratio just like the backend gets
+// it, but without the visual bell.  The cursor is not included.
+func (t *TGraphicsOutput) Screenshot(opts ScreenshotOptions) *image.RGBA {
+	mem := t.v.videoMemory()
+	if opts.Border {
+		screen := image.NewRGBA(image.Rect(0, 0, 640, 500))
+		t.v.drawScreen(screen, &mem, opts.Flash)
+		img := image.NewRGBA(image.Rect(0, 0, MonitorWidth, MonitorHeight))
+		t.v.drawMonitor(img, screen, false)
+		return img
+	}
+	width, height := t.v.screenSize()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, t.v.handleFlash(mem[y][x], opts.Flash))
+		}
+	}
+	return img
+}
+
+// IndexedScreenshot returns a paletted image of the screen at its own resolution
+// whose palette indices are the logical colours in video memory.  The palette is the
+// colour lookup table in the given flash phase, so the image still looks right.
+func (t *TGraphicsOutput) IndexedScreenshot(flash FlashPhase) *image.Paletted {
+	mem := t.v.videoMemory()
+	palette := color.Palette{}
+	for c := 0; c < 16; c++ {
+		palette = append(palette, t.v.handleFlash(c, flash))
+	}
+	width, height := t.v.screenSize()
+	img := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetColorIndex(x, y, uint8(mem[y][x]&15))
+		}
+	}
+	return img
+}
+
+// SaveScreenshot saves a Screenshot to a PNG file.
+func (t *TGraphicsOutput) SaveScreenshot(path string, opts ScreenshotOptions) error {
+	return savePNG(path, t.Screenshot(opts))
+}
+
+// SaveIndexedScreenshot saves an IndexedScreenshot to a PNG file.
+func (t *TGraphicsOutput) SaveIndexedScreenshot(path string, flash FlashPhase) error {
+	return savePNG(path, t.IndexedScreenshot(flash))
+}
+
+// savePNG writes img to a PNG file.
+func savePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
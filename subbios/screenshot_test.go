@@ -0,0 +1,66 @@
+package subbios
+
+import (
+	"image"
+	"testing"
+
+	"github.com/adamstimb/nimgobus/subbios/colour"
+)
+
+func TestScreenshot(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	s.Stdio.Printf("\x1b~F") // Hide cursor
+	s.TGraphicsOutput.FSetCltElement(1, colour.LightRed, colour.FastFlash, colour.Yellow)
+	s.TGraphicsOutput.FFillArea(1, 0, 1, 0, 0, []int{0, 0, 99, 0, 99, 99, 0, 99})
+
+	tests := []struct {
+		opts   ScreenshotOptions
+		size   image.Point
+		x, y   int
+		colour int
+	}{
+		{ScreenshotOptions{Flash: FlashFirst}, image.Pt(640, 250), 50, 200, colour.LightRed},
+		{ScreenshotOptions{Flash: FlashSecond}, image.Pt(640, 250), 50, 200, colour.Yellow},
+		{ScreenshotOptions{Flash: FlashSecond}, image.Pt(640, 250), 200, 200, colour.Black},
+		{ScreenshotOptions{Border: true, Flash: FlashFirst}, image.Pt(MonitorWidth, MonitorHeight), 100, 500, colour.LightRed},
+	}
+	for _, tt := range tests {
+		img := s.TGraphicsOutput.Screenshot(tt.opts)
+		if img.Bounds().Size() != tt.size {
+			t.Errorf("Screenshot(%+v) is %v, expected %v", tt.opts, img.Bounds().Size(), tt.size)
+		}
+		if c := img.RGBAAt(tt.x, tt.y); c != colour.PhysicalColours[tt.colour] {
+			t.Errorf("Screenshot(%+v) pixel at %d, %d is %v, expected %v", tt.opts, tt.x, tt.y, c, colour.PhysicalColours[tt.colour])
+		}
+	}
+
+	img := s.TGraphicsOutput.IndexedScreenshot(FlashSecond)
+	if i := img.ColorIndexAt(50, 200); i != 1 {
+		t.Errorf("IndexedScreenshot pixel at 50, 200 has index %d, expected 1", i)
+	}
+	if c := img.Palette[1]; c != colour.PhysicalColours[colour.Yellow] {
+		t.Errorf("IndexedScreenshot palette[1] is %v, expected %v", c, colour.PhysicalColours[colour.Yellow])
+	}
+}
+
+func TestScreenshotBell(t *testing.T) {
+	b := NewImageBackend(nil)
+	s := Subbios{}
+	s.InitWithBackend(b)
+	t.Cleanup(s.Close)
+	border := colour.PhysicalColours[colour.DefaultLowResColours[0].FirstPhysicalColour]
+	flash := colour.PhysicalColours[15-colour.DefaultLowResColours[0].FirstPhysicalColour]
+	// A screenshot just after BEL has the plain border and leaves the flash for the
+	// next frame
+	s.Stdio.Printf("\x07")
+	img := s.TGraphicsOutput.Screenshot(ScreenshotOptions{Border: true})
+	if c := img.RGBAAt(0, 0); c != border {
+		t.Errorf("screenshot border pixel is %v, expected %v", c, border)
+	}
+	s.Update()
+	if c := b.Image().(*image.RGBA).RGBAAt(0, 0); c != flash {
+		t.Errorf("border pixel is %v after the screenshot, expected %v", c, flash)
+	}
+}
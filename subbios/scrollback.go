@@ -0,0 +1,188 @@
+package subbios
+
+// defaultScrollback is how many lines the scrollback keeps until SetScrollback says
+// otherwise.
+const defaultScrollback = 1000
+
+// SetScrollback sets how many lines that have scrolled off the top of the scrolling
+// area are kept in the scrollback, dropping the oldest ones if there are too many
+// already.  0 turns the scrollback off.  The default is 1000.
+func (sio *Stdio) SetScrollback(lines int) error {
+	if lines < 0 {
+		return invalidParameter("SetScrollback", "lines", lines)
+	}
+	c := sio.c
+	c.muScrollback.Lock()
+	defer c.muScrollback.Unlock()
+	c.scrollbackSize = lines
+	c.trimScrollback()
+	return nil
+}
+
+// GetScrollback returns a copy of the lines in the scrollback, oldest first.  Each
+// line is as wide as the scrolling area was when it scrolled off the top.
+func (sio *Stdio) GetScrollback() [][]Cell {
+	c := sio.c
+	c.muScrollback.Lock()
+	defer c.muScrollback.Unlock()
+	lines := make([][]Cell, len(c.scrollback))
+	for i, line := range c.scrollback {
+		lines[i] = append([]Cell{}, line...)
+	}
+	return lines
+}
+
+// ScrollBack moves the view of the scrolling area up through the scrollback by lines,
+// or back down if lines is negative.  The program carries on printing to the live
+// screen underneath and the view stays put while it does, until it's scrolled back
+// down to the bottom or LeaveScrollback is called.  By default SHIFT+PAGE UP and
+// SHIFT+PAGE DOWN do this a page at a time, and any other key goes back to the live
+// view.
+func (sio *Stdio) ScrollBack(lines int) {
+	c := sio.c
+	c.muScrollback.Lock()
+	defer c.muScrollback.Unlock()
+	c.scrollbackOffset += lines
+	c.clampScrollbackOffset()
+}
+
+// LeaveScrollback goes back to the live view of the scrolling area.
+func (sio *Stdio) LeaveScrollback() {
+	sio.ScrollBack(-sio.ScrollbackOffset())
+}
+
+// ScrollbackOffset returns how many lines up the scrollback the view is, or 0 if
+// it's showing the live screen.
+func (sio *Stdio) ScrollbackOffset() int {
+	c := sio.c
+	c.muScrollback.Lock()
+	defer c.muScrollback.Unlock()
+	return c.scrollbackOffset
+}
+
+// saveScrollback copies the top n rows of the scrolling area into the scrollback
+// before they're scrolled away.
+func (c *console) saveScrollback(n int) {
+	h, w := c.getScrollingAreaSize()
+	if n > h {
+		n = h
+	}
+	c.muCells.Lock()
+	lines := make([][]Cell, n)
+	for row := 1; row <= n; row++ {
+		r, cl := c.absoluteCell(row, 1)
+		lines[row-1] = append([]Cell{}, c.cells[r][cl:cl+w]...)
+	}
+	c.muCells.Unlock()
+	c.muScrollback.Lock()
+	defer c.muScrollback.Unlock()
+	if c.scrollbackSize == 0 {
+		return
+	}
+	c.scrollback = append(c.scrollback, lines...)
+	// Keep the view on the same lines while the program carries on
+	if c.scrollbackOffset > 0 {
+		c.scrollbackOffset += n
+	}
+	c.trimScrollback()
+}
+
+// trimScrollback drops the oldest lines if there are more than scrollbackSize.
+func (c *console) trimScrollback() {
+	if extra := len(c.scrollback) - c.scrollbackSize; extra > 0 {
+		c.scrollback = append([][]Cell{}, c.scrollback[extra:]...)
+	}
+	c.clampScrollbackOffset()
+}
+
+// clampScrollbackOffset keeps the view between the live screen and the oldest line.
+func (c *console) clampScrollbackOffset() {
+	if c.scrollbackOffset > len(c.scrollback) {
+		c.scrollbackOffset = len(c.scrollback)
+	}
+	if c.scrollbackOffset < 0 {
+		c.scrollbackOffset = 0
+	}
+}
+
+// scrollbackKeys handles SHIFT+PAGE UP and SHIFT+PAGE DOWN, and leaves the scrollback
+// if anything else is pressed.  It returns true if the keys have been dealt with.
+func (c *console) scrollbackKeys(in Input) bool {
+	h, _ := c.getScrollingAreaSize()
+	c.muScrollback.Lock()
+	defer c.muScrollback.Unlock()
+	if in.KeyPressDuration(KeyShift) > 0 {
+		switch {
+		case repeatingKeyPressed(in, KeyPageUp):
+			c.scrollbackOffset += h
+			c.clampScrollbackOffset()
+			return true
+		case repeatingKeyPressed(in, KeyPageDown):
+			c.scrollbackOffset -= h
+			c.clampScrollbackOffset()
+			return true
+		}
+	}
+	if c.scrollbackOffset > 0 && anyKeyPressed(in) {
+		c.scrollbackOffset = 0
+	}
+	return false
+}
+
+// anyKeyPressed returns true if a key that does something has just been pressed.
+// Holding down SHIFT, CTRL or ALT on its own doesn't count.
+func anyKeyPressed(in Input) bool {
+	for _, r := range in.AppendInputChars(nil) {
+		if r != 0 {
+			return true
+		}
+	}
+	for k := Key(0); k < keyCount; k++ {
+		if k != KeyShift && k != KeyControl && k != KeyAlt && in.KeyPressDuration(k) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// drawScrollback draws the view of the scrolling area on the video memory overlay if
+// it's scrolled back, and returns true if it did.  The top rows come from the
+// scrollback and the rest are the top of the live screen pushed down.
+func (c *console) drawScrollback() bool {
+	c.muScrollback.Lock()
+	defer c.muScrollback.Unlock()
+	offset := c.scrollbackOffset
+	if offset == 0 {
+		return false
+	}
+	h, w := c.getScrollingAreaSize()
+	v := c.v
+	v.muVideoMemoryOverlay.Lock()
+	defer v.muVideoMemoryOverlay.Unlock()
+	// Push the live rows down, bottom first
+	x1, _ := c.convertAnyCurposToXY(1, 1)
+	x2 := x1 + w*8
+	for row := h; row > offset; row-- {
+		_, dstY := c.convertAnyCurposToXY(row, 1)
+		_, srcY := c.convertAnyCurposToXY(row-offset, 1)
+		for i := 0; i < 10; i++ {
+			copy(v.videoMemoryOverlay[249-(dstY+i)][x1:x2], v.memory[249-(srcY+i)][x1:x2])
+		}
+	}
+	// Then the scrollback
+	for row := 1; row <= h && row <= offset; row++ {
+		line := c.scrollback[len(c.scrollback)-offset+row-1]
+		for col := 1; col <= w; col++ {
+			cell := c.blankCell()
+			if col <= len(line) {
+				cell = line[col-1]
+			}
+			x, y := c.convertAnyCurposToXY(row, col)
+			img := v.makeConsoleCharImg(cell.Char, cell.Pen, cell.Paper, cell.CharSet, cell.Underlined)
+			for i := 0; i < 10; i++ {
+				copy(v.videoMemoryOverlay[249-(y+i)][x:x+8], img[9-i])
+			}
+		}
+	}
+	return true
+}
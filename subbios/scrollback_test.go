@@ -0,0 +1,70 @@
+package subbios
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestScrollback(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	sio := &s.Stdio
+	c := sio.c
+	if err := sio.SetScrollback(-1); errorCode(err) == 0 {
+		t.Errorf("SetScrollback(-1) returned %v, want EInvalidParameter", err)
+	}
+	sio.SetScrollback(3)
+	sio.Printf("\x1b[1;1;5;20~B")
+	for i := 1; i <= 8; i++ {
+		sio.Printf(fmt.Sprintf("\r\nline %d", i))
+	}
+	lines := sio.GetScrollback()
+	if len(lines) != 3 || len(lines[0]) != 20 {
+		t.Fatalf("got %d lines in the scrollback, want 3 of 20 cells", len(lines))
+	}
+	for i, want := range []string{"line 1", "line 2", "line 3"} {
+		got := ""
+		for _, cell := range lines[i][:6] {
+			got += string(rune(cell.Char))
+		}
+		if got != want {
+			t.Errorf("scrollback line %d is %q, want %q", i, got, want)
+		}
+	}
+
+	// Two lines up the live rows are pushed down by two
+	sio.ScrollBack(2)
+	s.Flush()
+	c.v.videoMemoryOverlay = c.v.memory
+	if !c.drawScrollback() {
+		t.Fatalf("drawScrollback didn't draw anything at offset %d", sio.ScrollbackOffset())
+	}
+	for y := 0; y < 30; y++ {
+		if c.v.videoMemoryOverlay[y+20] != c.v.memory[y] {
+			t.Errorf("row %d of the overlay isn't row %d of the live screen", y+20, y)
+			break
+		}
+	}
+	// New lines don't move the view
+	sio.Printf("\r\nline 9")
+	if got := sio.ScrollbackOffset(); got != 3 {
+		t.Errorf("offset is %d after another line, want 3", got)
+	}
+	sio.ScrollBack(10)
+	if got := sio.ScrollbackOffset(); got != 3 {
+		t.Errorf("offset is %d after scrolling off the top, want 3", got)
+	}
+	c.update(testInput{chars: "x"})
+	if got := sio.ScrollbackOffset(); got != 0 {
+		t.Errorf("offset is %d after typing, want 0", got)
+	}
+	c.update(testInput{keys: pressed(KeyShift, KeyPageUp)})
+	if got := sio.ScrollbackOffset(); got != 3 {
+		t.Errorf("offset is %d after SHIFT+PAGE UP, want 3", got)
+	}
+	sio.LeaveScrollback()
+	if c.drawScrollback() {
+		t.Errorf("drawScrollback drew the scrollback after leaving it")
+	}
+}
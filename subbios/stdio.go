@@ -0,0 +1,194 @@
+package subbios
+
+import (
+	"time"
+)
+
+// Stdio implements all the console commands in kind-of old-skool C stylee.
+type Stdio struct {
+	s                                    *Subbios
+	c                                    *console
+	SuppressCtrlCInterrupt               bool // Set to true to prevent CTRL-C keyboard interrupts breaking input/output (you can still check the status though).
+	SuppressCtrlShiftScrollLockInterrupt bool // Set to true to prevent CTRL-SHIFT-SCROLL LOCK keyboard interrupts  breaking input/output (you can still check the status though).
+	RawInput                             bool // Set to true to make Read return keys as soon as they're typed, without echoing them, instead of a line at a time.
+	ctrlCInterrupt                       bool
+	ctrlShiftScrollLock                  bool
+	partialWrite                         []byte // The start of a UTF-8 char that Write is waiting for the rest of
+	readBuffer                           []byte // What Read has got from the keyboard but not returned yet
+}
+
+// checkKeyboardInterrupts will set the relevant interrupt flag if
+// a keyboard interrupt is sent by the user.
+func (sio *Stdio) checkKeyboardInterrupts(in Input) {
+
+	if ctrlCPressed(in) {
+		sio.ctrlCInterrupt = true
+	}
+
+	if in.KeyPressDuration(KeyControl) > 1 && in.KeyPressDuration(KeyShift) > 1 &&
+		in.KeyPressDuration(KeyScrollLock) > 1 {
+		sio.ctrlShiftScrollLock = true
+	}
+
+}
+
+// ctrlCPressed returns true if CTRL+C is being held down.
+func ctrlCPressed(in Input) bool {
+	return in.KeyPressDuration(KeyControl) > 1 && in.KeyPressDuration(KeyC) > 1
+}
+
+// GetCtrlCInterrupt checks if a CTRL+C interrupt flag is set.  Passing true will unset
+// the flag after checking, otherwise the flag remains unchanged.
+func (sio *Stdio) GetCtrlCInterrupt(unsetAfterChecking bool) bool {
+	val := sio.ctrlCInterrupt
+	if val {
+		time.Sleep(250 * time.Millisecond)
+	}
+	if unsetAfterChecking {
+		sio.ctrlCInterrupt = false
+	}
+	return val
+}
+
+// GetCtrlShiftScrollLockInterrupt checks if the CTRL+SHIFT+SCROLL LOCK interrupt flag is
+// set. Passing true will unset the flag after checking, otherwise the flag remains unchanged.
+func (sio *Stdio) GetCtrlShiftScrollLockInterrupt(unsetAfterChecking bool) bool {
+	val := sio.ctrlShiftScrollLock
+	if val {
+		time.Sleep(250 * time.Millisecond)
+	}
+	if unsetAfterChecking {
+		sio.ctrlShiftScrollLock = false
+	}
+	return val
+}
+
+// gotAnyInterrupts returns true if either CTRL+C or CTRL+SHIFT+SCROLL LOCK interrupt flags
+// are set.
+func (sio *Stdio) gotAnyInterrupts() bool {
+	if (sio.GetCtrlCInterrupt(false) && !sio.SuppressCtrlCInterrupt) ||
+		(sio.GetCtrlShiftScrollLockInterrupt(false) && !sio.SuppressCtrlShiftScrollLockInterrupt) {
+		return true
+	}
+	return false
+}
+
+// GetCurpos returns the current cursor position and is a bit of cheat really but nvm.
+func (sio *Stdio) GetCurpos() (row, col int) {
+	return sio.c.cursorPositionReport()
+}
+
+// SetAttributes switches the bold, faint, reverse video and concealed SGR attributes
+// (SGR 1, 2, 7 and 8) on or off.  They're off to begin with, as they were on the
+// Nimbus, so those SGR parameters are ignored unless you switch them on.  In ANSI mode
+// they're always on.
+func (sio *Stdio) SetAttributes(on bool) {
+	sio.c.attributesOn = on
+}
+
+// Printf imitates C's printf command but does not support formatting strings - use Writef
+// or fmt.Fprintf for that.  The escape chars for newline `\n` and `\t` tab are supported.
+// ANSI escape sequences can also be sent with this function - [a complete description of supported escape sequences and their effects is given below](#escape-sequences).
+func (sio *Stdio) Printf(st string) {
+	_ = sio.GetCtrlCInterrupt(true)
+	_ = sio.GetCtrlShiftScrollLockInterrupt(true)
+	// Load the buffer and flush it
+	for _, r := range st {
+		if sio.gotAnyInterrupts() {
+			return
+		}
+		sio.c.stdoutBuffer.Enqueue(r)
+	}
+	sio.c.flushStdoutBuffer()
+}
+
+// Putchar prints a single rune to the screen.
+func (sio *Stdio) Putchar(r rune) {
+	sio.c.stdoutBuffer.Enqueue(r)
+	sio.c.flushStdoutBuffer()
+}
+
+// Putchars prints a slice of runes to the screen.
+func (sio *Stdio) Putchars(runes []rune) {
+	for _, r := range runes {
+		sio.c.stdoutBuffer.Enqueue(r)
+	}
+	sio.c.flushStdoutBuffer()
+}
+
+// repeatingKeyPressed return true when key is pressed considering the repeat state.
+func repeatingKeyPressed(in Input, key Key) bool {
+	const (
+		delay    = 30
+		interval = 3
+	)
+	d := in.KeyPressDuration(key)
+	if d == 1 {
+		return true
+	}
+	if d >= delay && (d-delay)%interval == 0 {
+		return true
+	}
+	return false
+}
+
+// Getchar gets a rune from keyboard input buffer without waiting for ENTER to be pressed.
+// Stdio.KeyboardBufferFlush() should be called first though!  Keys that don't type a char
+// come through as key codes (see KeyCodeLeft and friends) and ReadKey takes them apart.
+func (sio *Stdio) Getchar() (r rune) {
+	_ = sio.GetCtrlCInterrupt(true)
+	_ = sio.GetCtrlShiftScrollLockInterrupt(true)
+	for {
+		if sio.gotAnyInterrupts() {
+			return 0
+		}
+		newr, ok := sio.c.stdinBuffer.Dequeue()
+		if !ok {
+			time.Sleep(5 * time.Millisecond)
+			continue
+		}
+		if newr == 0 {
+			time.Sleep(5 * time.Millisecond)
+			continue
+		} else {
+			r = newr
+			break
+		}
+	}
+	return r
+}
+
+// Getch gets a rune from keyboard input buffer or returns 0 if the buffer is empty.  Keys
+// that don't type a char come through as key codes, as they do for Getchar.
+func (sio *Stdio) Getch() (r rune) {
+	_ = sio.GetCtrlCInterrupt(true)
+	_ = sio.GetCtrlShiftScrollLockInterrupt(true)
+
+	if sio.gotAnyInterrupts() {
+		time.Sleep(5 * time.Millisecond)
+		return 0
+	}
+
+	if r, ok := sio.c.stdinBuffer.Dequeue(); ok {
+		time.Sleep(5 * time.Millisecond)
+		return r
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	return 0
+}
+
+// KeyboardBufferFlush removes all chars from the keyboard input buffer.  It should be called before
+// Scanf() or Getchar().
+func (sio *Stdio) KeyboardBufferFlush() {
+	sio.c.stdinBuffer.Reset()
+	sio.c.stdinBufferIndex = 0
+}
+
+// Scanf is a little bit different to the usual Scanf.  A buffer of runes (which can be empty but not nil)
+// is passed to the function and echoed into the scrolling area from the current cursor position.  The user
+// can then edit the buffer as they please and the changes returned via the buffer's pointer when they hit
+// ENTER.  See ScanfWithOptions for the keys and the extras.
+func (sio *Stdio) Scanf(textBuffer *[]rune) {
+	sio.ScanfWithOptions(textBuffer, ScanfOptions{})
+}
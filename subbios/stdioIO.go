@@ -0,0 +1,105 @@
+package subbios
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/adamstimb/nimgobus/subbios/errorcode"
+)
+
+// Write prints p on the screen as UTF-8 text, escape sequences and all, so Stdio is an
+// io.Writer and fmt.Fprintf, log.New and text/template can all write straight to the
+// console.  A UTF-8 char split between two Writes is held back until the rest of it
+// arrives.  If CTRL+C or CTRL+SHIFT+SCROLL LOCK breaks in it stops, with how much of
+// p it got through and an error that errors.Is matches with ErrInterrupted.
+func (sio *Stdio) Write(p []byte) (n int, err error) {
+	_ = sio.GetCtrlCInterrupt(true)
+	_ = sio.GetCtrlShiftScrollLockInterrupt(true)
+	return sio.write(p)
+}
+
+// write is Write without unsetting the keyboard interrupt flags first.
+func (sio *Stdio) write(p []byte) (n int, err error) {
+	held := len(sio.partialWrite)
+	b := append(sio.partialWrite, p...)
+	sio.partialWrite = nil
+	for i := 0; i < len(b); {
+		if sio.gotAnyInterrupts() {
+			sio.c.flushStdoutBuffer()
+			if i < held {
+				// Keep what was held back from last time, which has already been
+				// counted as written
+				sio.partialWrite = append([]byte{}, b[i:held]...)
+				return 0, newError("Write", errorcode.EInterrupted)
+			}
+			return i - held, newError("Write", errorcode.EInterrupted)
+		}
+		if !utf8.FullRune(b[i:]) {
+			sio.partialWrite = append([]byte{}, b[i:]...)
+			break
+		}
+		r, size := utf8.DecodeRune(b[i:])
+		sio.c.stdoutBuffer.Enqueue(r)
+		i += size
+	}
+	sio.c.flushStdoutBuffer()
+	return len(p), nil
+}
+
+// Writef is a real printf: it formats according to format like fmt.Printf and
+// prints the result on the screen.
+func (sio *Stdio) Writef(format string, a ...any) (n int, err error) {
+	return fmt.Fprintf(sio, format, a...)
+}
+
+// Read reads from the keyboard buffer as UTF-8, so Stdio is an io.Reader and
+// bufio.Scanner and friends can read from the console.  Normally it reads a line at
+// a time, which the user types and edits with Scanf, and the line ends with \n.  If
+// RawInput is set it returns whatever's been typed as soon as there's anything,
+// without echoing it, and the special keys come through as they do for Getch.  It
+// only fails if it's interrupted, with an error that errors.Is matches with
+// ErrInterrupted, since there's always more keyboard.
+func (sio *Stdio) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(sio.readBuffer) == 0 {
+		if sio.RawInput {
+			err = sio.readRaw()
+		} else {
+			err = sio.readLine()
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	n = copy(p, sio.readBuffer)
+	sio.readBuffer = sio.readBuffer[n:]
+	return n, nil
+}
+
+// readLine gets a line with Scanf, echoes the new line and puts it in the read buffer.
+func (sio *Stdio) readLine() error {
+	line := []rune{}
+	sio.Scanf(&line)
+	if sio.gotAnyInterrupts() {
+		return newError("Read", errorcode.EInterrupted)
+	}
+	sio.Printf("\r\n")
+	sio.readBuffer = append([]byte(string(line)), '\n')
+	return nil
+}
+
+// readRaw waits for at least one rune in the keyboard buffer and puts it and any
+// others that are waiting in the read buffer.
+func (sio *Stdio) readRaw() error {
+	r := sio.Getchar()
+	if sio.gotAnyInterrupts() {
+		return newError("Read", errorcode.EInterrupted)
+	}
+	for r != 0 {
+		sio.readBuffer = utf8.AppendRune(sio.readBuffer, r)
+		r, _ = sio.c.stdinBuffer.Dequeue()
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package subbios
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// typeKeys puts runes in the keyboard buffer one at a time, like someone typing.
+func typeKeys(sio *Stdio, keys string) {
+	for _, r := range keys {
+		time.Sleep(20 * time.Millisecond)
+		sio.c.stdinBuffer.Enqueue(r)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	sio := &s.Stdio
+	fmt.Fprintf(sio, "%d green bottles\r\n", 10)
+	sio.Write([]byte("cost \xc2"))
+	sio.Write([]byte("\xa31"))
+	if n, err := sio.Writef("\r\n%s", "ok"); n != 4 || err != nil {
+		t.Errorf("Writef returned %d, %v, want 4, nil", n, err)
+	}
+	// Interrupted before the rest of a char arrives, the start of it is kept
+	sio.Write([]byte("\xc2"))
+	sio.ctrlCInterrupt = true
+	if n, err := sio.write([]byte("\xa3x")); n != 0 || !errors.Is(err, ErrInterrupted) {
+		t.Errorf("interrupted write returned %d, %v, want 0, %v", n, err, ErrInterrupted)
+	}
+	sio.ctrlCInterrupt = false
+	sio.Write([]byte("\xa3x"))
+	got := sio.GetScreenText()
+	for i, want := range []string{"10 green bottles", "cost £1", "ok£x"} {
+		if got[i] != want {
+			t.Errorf("row %d is %q, want %q", i+1, got[i], want)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	sio := &s.Stdio
+	sio.Printf("> ")
+	go typeKeys(sio, "hellx\bo\nworld\n")
+	scanner := bufio.NewScanner(sio)
+	for _, want := range []string{"hello", "world"} {
+		if !scanner.Scan() {
+			t.Fatalf("Scan failed: %v", scanner.Err())
+		}
+		if got := scanner.Text(); got != want {
+			t.Errorf("read %q, want %q", got, want)
+		}
+	}
+	if got := sio.GetScreenText(); got[0] != "> hello" || got[1] != "world" {
+		t.Errorf("screen shows %q, want the lines echoed", got[:3])
+	}
+
+	sio.RawInput = true
+	sio.c.stdinBuffer.Enqueue('a')
+	sio.c.stdinBuffer.Enqueue('£')
+	b := make([]byte, 2)
+	if n, _ := sio.Read(b); string(b[:n]) != "a\xc2" {
+		t.Errorf("raw Read got %q, want %q", b[:n], "a\xc2")
+	}
+	if n, _ := sio.Read(b); string(b[:n]) != "\xa3" {
+		t.Errorf("raw Read got %q, want %q", b[:n], "\xa3")
+	}
+}
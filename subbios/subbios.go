@@ -0,0 +1,153 @@
+// Subbios provides all commands to control the Nimbus.  It's similar (but different) to the original
+// RM Nimbus SUBBIOS but has the advantage that you don't need to write assembler!
+package subbios
+
+import (
+	"image"
+
+	"github.com/adamstimb/nimgobus/internal/queue"
+	"github.com/adamstimb/nimgobus/subbios/errorcode"
+)
+
+// The subbios commands are grouped by Type as represented by this struct.
+type Subbios struct {
+	backend         Backend
+	input           Input
+	borderSize      int
+	FunctionStatus  int
+	FunctionError   int
+	THardSums       THardSums
+	TGraphicsOutput TGraphicsOutput
+	TRawConsole     tRawConsole
+	TGraphicsInput  TGraphicsInput
+	TSound          TSound
+	TKeyboard       TKeyboard
+	Stdio           Stdio
+}
+
+// Initializes the subbios commands with a headless ImageBackend.  Use Nimbus.Init
+// if you want the Ebiten monitor.
+func (s *Subbios) Init() {
+	s.InitWithBackend(NewImageBackend(nil))
+}
+
+// InitWithBackend initializes the subbios commands and renders the monitor on b.
+func (s *Subbios) InitWithBackend(b Backend) {
+	s.borderSize = 50
+	s.backend = b
+	s.input, _ = b.(Input)
+	// Initialize video
+	monitorImage := image.NewRGBA(image.Rect(0, 0, MonitorWidth, MonitorHeight))
+	screenImage := image.NewRGBA(image.Rect(0, 0, 640, 500))
+	// Initialize Subbios functions/devices
+	s.FunctionStatus = 0
+	s.FunctionError = errorcode.EOk
+	s.THardSums = THardSums{s: s}
+	s.TGraphicsOutput = TGraphicsOutput{
+		s: s, v: &video{
+			backend:      b,
+			headless:     s.input == nil,
+			monitorImage: monitorImage,
+			borderSize:   s.borderSize,
+			screenImage:  screenImage,
+			borderColour: 0,
+			done:         make(chan struct{}),
+		},
+		On: false}
+	s.TGraphicsOutput.v.loadCharsetImages(0)
+	s.TGraphicsOutput.v.loadCharsetImages(1)
+	s.TGraphicsInput = TGraphicsInput{
+		s: s,
+		v: s.TGraphicsOutput.v,
+	}
+	s.TSound = TSound{s: s, psg: newPSG(), music: newMusic()}
+	s.TKeyboard = TKeyboard{s: s}
+	s.Stdio = Stdio{
+		s: s,
+		c: &console{
+			curpos:         [2]int{1, 1},
+			stdoutBuffer:   queue.New[rune](),
+			stdinBuffer:    queue.New[rune](),
+			scrollbackSize: defaultScrollback,
+			v:              s.TGraphicsOutput.v,
+			lowResColourLookupTable: [16][3]int{
+				{0, 0, 0},
+				{2, 0, 0},
+				{4, 0, 0},
+				{6, 0, 0},
+				{1, 0, 0},
+				{3, 0, 0},
+				{5, 0, 0},
+				{7, 0, 0},
+				{8, 0, 0},
+				{10, 0, 0},
+				{12, 0, 0},
+				{14, 0, 0},
+				{9, 0, 0},
+				{11, 0, 0},
+				{13, 0, 0},
+				{15, 0, 0},
+			},
+			hiResColourLookupTable: [4][3]int{
+				{0, 0, 0},
+				{7, 0, 0},
+				{15, 0, 1},
+				{15, 0, 0},
+			},
+			scrollingArea: [4]int{1, 1, 25, 80},
+		},
+	}
+	s.TGraphicsOutput.v.con = s.Stdio.c
+	s.Stdio.c.bell = s.TSound.Bell
+	s.TGraphicsOutput.FGraphicsOutputColdStart()
+	s.Stdio.c.resetToInitialState()
+	s.loadLogoImage()
+	// Start background processes
+	go s.TGraphicsOutput.v.colourFlashTicker(s.TGraphicsOutput.v.done)
+	if a, ok := b.(AudioBackend); ok {
+		// If it can't, PLAY and BEL carry on as if it were headless
+		s.TSound.audible = a.PlayAudio(s.TSound.Stream()) == nil
+	}
+}
+
+// Close stops the background processes started by Init, so the Subbios can be
+// garbage collected.  Don't use it again afterwards.
+func (s *Subbios) Close() {
+	v := s.TGraphicsOutput.v
+	if v == nil || v.done == nil {
+		return
+	}
+	close(v.done)
+	v.done = nil
+}
+
+// Update needs to be called on each Ebiten update, ideally by Nimbus.Update().  With a
+// headless backend call it whenever you want the backend to receive a fresh image.
+func (s *Subbios) Update() {
+	s.TGraphicsOutput.v.update()
+	if !s.TSound.audible {
+		s.TSound.update()
+	}
+	// Skip input polling if the backend hasn't got any
+	if s.input == nil {
+		return
+	}
+	s.TGraphicsInput.update(s.input)
+	s.TKeyboard.update(s.input)
+	s.Stdio.c.update(s.input)
+	s.Stdio.checkKeyboardInterrupts(s.input)
+	s.TSound.checkKeyboardInterrupts(s.input)
+}
+
+// Flush writes everything waiting in the draw queue to video memory straight away
+// rather than waiting for the next Update.
+func (s *Subbios) Flush() {
+	s.TGraphicsOutput.v.flushDrawQueue()
+}
+
+// VideoMemory flushes the draw queue and returns a copy of the video memory: the
+// logical colour of every pixel, with row 0 at the top of the screen.  Only the
+// first 320 columns are used in 40-column mode.  The cursor is not included.
+func (s *Subbios) VideoMemory() [250][640]int {
+	return s.TGraphicsOutput.v.videoMemory()
+}
@@ -0,0 +1,162 @@
+// Package subbiostest drives a headless Subbios in tests and compares what it drew
+// with golden images.
+package subbiostest
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adamstimb/nimgobus/subbios"
+	"github.com/adamstimb/nimgobus/subbios/colour"
+)
+
+var update = flag.Bool("update", false, "write the golden images in testdata instead of comparing with them")
+
+// New returns a headless Subbios with the graphics system switched on and the
+// console in its initial state (80 columns, cursor top-left).  It's closed when the
+// test finishes.
+func New(t testing.TB) *subbios.Subbios {
+	t.Helper()
+	s := &subbios.Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// AssertGolden flushes the draw queue and compares the video memory with the
+// golden image testdata/<name>.png.  If they differ then testdata/<name>.got.png
+// and testdata/<name>.diff.png are written and the test fails.  Run the tests with
+// -update to write the golden image instead.
+func AssertGolden(t testing.TB, s *subbios.Subbios, name string) {
+	t.Helper()
+	got := s.VideoMemory()
+	path := filepath.Join("testdata", name+".png")
+	if *update {
+		if err := writePNG(path, Encode(got)); err != nil {
+			t.Fatalf("couldn't write golden image: %v", err)
+		}
+		return
+	}
+	want, err := readGolden(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("golden image %s doesn't exist, run the tests with -update to create it", path)
+	}
+	if err != nil {
+		t.Fatalf("couldn't read golden image: %v", err)
+	}
+	diff, n := Diff(want, got)
+	if n == 0 {
+		// Tidy up after any previous failure
+		os.Remove(filepath.Join("testdata", name+".got.png"))
+		os.Remove(filepath.Join("testdata", name+".diff.png"))
+		return
+	}
+	gotPath := filepath.Join("testdata", name+".got.png")
+	diffPath := filepath.Join("testdata", name+".diff.png")
+	if err := writePNG(gotPath, Encode(got)); err != nil {
+		t.Errorf("couldn't write %s: %v", gotPath, err)
+	}
+	if err := writePNG(diffPath, diff); err != nil {
+		t.Errorf("couldn't write %s: %v", diffPath, err)
+	}
+	t.Errorf("video memory differs from %s in %d pixels, see %s and %s", path, n, gotPath, diffPath)
+}
+
+// palette shows each logical colour as the physical colour of the same number, so
+// golden images are easy on the eye.  It's only for viewing: the comparison is done
+// on the palette indices.
+var palette = func() color.Palette {
+	p := color.Palette{}
+	for _, c := range colour.PhysicalColours {
+		p = append(p, c)
+	}
+	return p
+}()
+
+// Encode converts video memory to a paletted image whose palette indices are the
+// logical colours.
+func Encode(mem [250][640]int) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, 640, 250), palette)
+	for y := 0; y < 250; y++ {
+		for x := 0; x < 640; x++ {
+			img.SetColorIndex(x, y, uint8(mem[y][x]))
+		}
+	}
+	return img
+}
+
+// Decode converts a paletted image made by Encode back into video memory.
+func Decode(img image.Image) ([250][640]int, error) {
+	var mem [250][640]int
+	p, ok := img.(*image.Paletted)
+	if !ok {
+		return mem, fmt.Errorf("golden image is a %T, expected a paletted image", img)
+	}
+	if p.Bounds() != image.Rect(0, 0, 640, 250) {
+		return mem, fmt.Errorf("golden image is %v, expected 640x250", p.Bounds())
+	}
+	for y := 0; y < 250; y++ {
+		for x := 0; x < 640; x++ {
+			mem[y][x] = int(p.ColorIndexAt(x, y))
+		}
+	}
+	return mem, nil
+}
+
+// Diff compares two video memories and returns an image of the differences with the
+// number of pixels that differ.  Matching pixels are drawn dimmed and differing
+// pixels bright red.
+func Diff(want, got [250][640]int) (*image.RGBA, int) {
+	img := image.NewRGBA(image.Rect(0, 0, 640, 250))
+	n := 0
+	for y := 0; y < 250; y++ {
+		for x := 0; x < 640; x++ {
+			if want[y][x] != got[y][x] {
+				img.SetRGBA(x, y, color.RGBA{0xff, 0x00, 0x00, 0xff})
+				n++
+				continue
+			}
+			c := palette[got[y][x]%len(palette)].(color.RGBA)
+			img.SetRGBA(x, y, color.RGBA{c.R / 4, c.G / 4, c.B / 4, 0xff})
+		}
+	}
+	return img, n
+}
+
+// readGolden reads a golden image and decodes it into video memory.
+func readGolden(path string) ([250][640]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return [250][640]int{}, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return [250][640]int{}, err
+	}
+	return Decode(img)
+}
+
+// writePNG writes img to a PNG file, creating the directory if necessary.
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
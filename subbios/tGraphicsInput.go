@@ -0,0 +1,73 @@
+package subbios
+
+import (
+	"sync"
+)
+
+// TGraphicsInput implements the one surviving function of t_graphics_input.
+type TGraphicsInput struct {
+	s        *Subbios
+	v        *video
+	muStatus sync.Mutex
+	status   [3]int //x,y,b
+}
+
+// FEnquirePositionAndButtonStatus returns the x, y position of the mouse and the button status.  Unlike
+// in the original Nimbus the mouse position is always being monitored and does not need to be initialized.
+// There is also no support for resetting the origin or scale, since nimgobus applications run in Windowed
+// environments and this would be really weird to implement and use.  Instead, the x, y positions are simply
+// scaled down and reported relative to the nimgobus screen co-ordinates.
+func (t *TGraphicsInput) FEnquirePositionAndButtonStatus() (x, y, b int) {
+	t.muStatus.Lock()
+	defer t.muStatus.Unlock()
+	return t.status[0], t.status[1], t.status[2]
+}
+
+// Retrieves the current mouse position and translates it to a position on the Nimbus screen
+// if it's overlapping.
+func (t *TGraphicsInput) update(in Input) {
+	// Get mouse position on the monitor (we'll translate it later)
+	x, y := in.CursorPosition()
+	// Get button status
+	var b int
+	if in.IsMouseButtonPressed(MouseButtonRight) {
+		b = 1
+	}
+	if in.IsMouseButtonPressed(MouseButtonLeft) {
+		b = 2
+	}
+	if in.IsMouseButtonPressed(MouseButtonRight) && in.IsMouseButtonPressed(MouseButtonLeft) {
+		b = 3
+	}
+	// Translate x, y values
+	x -= t.v.borderSize
+	y -= t.v.borderSize
+	videoWidth := 640
+	if t.v.screenWidth == 40 {
+		videoWidth = 320
+	}
+	if videoWidth == 640 {
+		y = y / 2
+	} else {
+		x = x / 2
+		y = y / 2
+	}
+	y = 250 - y // Flip vertical
+	// Clamp values
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
+	if x > videoWidth {
+		x = videoWidth
+	}
+	if y > 250 {
+		y = 250
+	}
+	// Update tMouse status
+	t.muStatus.Lock()
+	defer t.muStatus.Unlock()
+	t.status = [3]int{x, y, b}
+}
@@ -0,0 +1,1541 @@
+package subbios
+
+import (
+	"math"
+
+	"github.com/adamstimb/nimgobus/internal/make2darray"
+	"github.com/adamstimb/nimgobus/sprite"
+	"github.com/adamstimb/nimgobus/subbios/colour"
+	"github.com/adamstimb/nimgobus/subbios/errorcode"
+)
+
+// TGraphicsOutput has all the t_graphics_output functions attached to it.
+// Each F* function reports failure by setting Subbios.FunctionError, and has an
+// error-returning variant of the same name without the F prefix.
+type TGraphicsOutput struct {
+	s  *Subbios
+	v  *video
+	On bool
+}
+
+// FGraphicsOutputColdStart initializes the graphics system.  If the graphics system
+// is not initialized with either cold start or warm start then the other graphics
+// functions will return the error ENotInitialized (except the colour lookup table and
+// border colour functions).
+// If the error EAlreadyOn is returned it may be useful to call function 4 (reinitialize)
+// to restore the screen to a known starting point.
+// WARNING - all of the internals of the graphics system are reinitialized whenever the
+// the screen width is changed, even when graphics are switched off.
+// On the Nimbus it was very important to switch off the graphics system before exiting
+// (FGraphicsOutputOff) before exiting - not so important here, but it's implemented
+// nonetheless.
+func (t *TGraphicsOutput) FGraphicsOutputColdStart() {
+	t.s.setFunctionError(t.GraphicsOutputColdStart())
+}
+
+// GraphicsOutputColdStart is the error-returning variant of FGraphicsOutputColdStart.
+func (t *TGraphicsOutput) GraphicsOutputColdStart() error {
+	// Handle already on
+	if t.On {
+		return newError("GraphicsOutputColdStart", errorcode.EAlreadyOn)
+	}
+	t.v.initLineStyles()
+	t.v.purgeDrawQueue()
+	t.v.resetColourLookupTable()
+	t.v.initDitherPatterns()
+	t.v.initHatchingPatterns()
+	t.v.initDitherLookupTables()
+	t.v.initHatchingLookupTables()
+	t.v.initPolymarkers()
+	t.v.resetClippingAreas()
+	t.v.resetVideoMemory()
+	t.v.con.clearCells()
+	// Set the flag and we're done
+	t.On = true
+	return nil
+}
+
+// FGraphicsOutputWarmStart is the same as FGraphicsOutputWarmStart except that it
+// does not re-initialize anything, e.g. user defined dither patterns.
+func (t *TGraphicsOutput) FGraphicsOutputWarmStart() {
+	t.s.setFunctionError(t.GraphicsOutputWarmStart())
+}
+
+// GraphicsOutputWarmStart is the error-returning variant of FGraphicsOutputWarmStart.
+func (t *TGraphicsOutput) GraphicsOutputWarmStart() error {
+	// Handle already on
+	if t.On {
+		return newError("GraphicsOutputWarmStart", errorcode.EAlreadyOn)
+	}
+	t.v.purgeDrawQueue()
+	t.v.resetVideoMemory()
+	t.v.con.clearCells()
+	// Set the flag and we're done
+	t.On = true
+	return nil
+}
+
+// FGraphicsOutputOff closes down the graphics system, which means that any future
+// access to TGraphicsOutput results in the error ENotInitialized (exceptions are
+// cold start, warm start, colour lookup table functions and border colour functions).
+func (t *TGraphicsOutput) FGraphicsOutputOff() {
+	t.s.setFunctionError(t.GraphicsOutputOff())
+}
+
+// GraphicsOutputOff is the error-returning variant of FGraphicsOutputOff.
+func (t *TGraphicsOutput) GraphicsOutputOff() error {
+	// Set the flag and we're done
+	t.On = false
+	return nil
+}
+
+// FReinitGraphicsOutput re-initializes the graphics system if it's currently on:
+// - the colour lookup table is initialized.
+// - hatching and dither patterns are set to their default values.
+// - clipping areas 1-9 are set to the full screen.
+// This function is executed during a screen width change and at start-up(?). If the
+// graphics system is currently switch off, ENotInitialized is returned, and the
+// function has not effect.
+func (t *TGraphicsOutput) FReinitGraphicsOutput() {
+	t.s.setFunctionError(t.ReinitGraphicsOutput())
+}
+
+// ReinitGraphicsOutput is the error-returning variant of FReinitGraphicsOutput.
+func (t *TGraphicsOutput) ReinitGraphicsOutput() error {
+	// Handle not on
+	if !t.On {
+		return newError("ReinitGraphicsOutput", errorcode.ENotInitialized)
+	}
+	t.v.initLineStyles()
+	t.v.initDitherPatterns()
+	t.v.initHatchingPatterns()
+	t.v.initDitherLookupTables()
+	t.v.initHatchingLookupTables()
+	t.v.initPolymarkers()
+	t.v.purgeDrawQueue()
+	t.v.resetColourLookupTable()
+	t.v.resetVideoMemory()
+	t.v.con.clearCells()
+	return nil
+}
+
+// FSetBorderColour sets the colour of the screen border.  The border cannot flash
+// and its colour is independent of screen resolution. For example, if the entry
+// parameter is 6, the border colour will be set to brown.  The error EInvalidParameter
+// is given if the border colour is not in the range 0-15.
+func (t *TGraphicsOutput) FSetBorderColour(c int) {
+	t.s.setFunctionError(t.SetBorderColour(c))
+}
+
+// SetBorderColour is the error-returning variant of FSetBorderColour.
+func (t *TGraphicsOutput) SetBorderColour(c int) error {
+	// Validate c
+	if c < 0 || c > 15 {
+		return invalidParameter("SetBorderColour", "c", c)
+	}
+	t.v.borderColour = c
+	return nil
+}
+
+// FGetBorder returns the current border colour.
+func (t *TGraphicsOutput) FGetBorderColour() int {
+	c, err := t.GetBorderColour()
+	t.s.setFunctionError(err)
+	return c
+}
+
+// GetBorderColour is the error-returning variant of FGetBorderColour.
+func (t *TGraphicsOutput) GetBorderColour() (int, error) {
+	return t.v.borderColour, nil
+}
+
+// FSetCltElement sets an element in the colour lookup table.
+// elementNumber can be in the range 0-15 for low-resolution mode, or 0-3 in high-resolution mode.
+// firstPhysicalColour and secondPhysicalColour can be in the range 0-15.
+// flashSpeed can be in the range 0-2: 0 - no flash, 1 - slow flash, 2 - fast flash.
+// If any parameters are out of range, the error EInvalidParameter is given.
+func (t *TGraphicsOutput) FSetCltElement(elementNumber, firstPhysicalColour, flashSpeed, secondPhysicalColour int) {
+	t.s.setFunctionError(t.SetCltElement(elementNumber, firstPhysicalColour, flashSpeed, secondPhysicalColour))
+}
+
+// SetCltElement is the error-returning variant of FSetCltElement.
+func (t *TGraphicsOutput) SetCltElement(elementNumber, firstPhysicalColour, flashSpeed, secondPhysicalColour int) error {
+	// Validate params
+	maxElement := 15
+	if t.v.screenWidth == 80 {
+		maxElement = 3
+	}
+	if elementNumber < 0 || elementNumber > maxElement {
+		return invalidParameter("SetCltElement", "elementNumber", elementNumber)
+	}
+	if firstPhysicalColour < 0 || firstPhysicalColour > 15 {
+		return invalidParameter("SetCltElement", "firstPhysicalColour", firstPhysicalColour)
+	}
+	if flashSpeed < 0 || flashSpeed > 2 {
+		return invalidParameter("SetCltElement", "flashSpeed", flashSpeed)
+	}
+	if secondPhysicalColour < 0 || secondPhysicalColour > 15 {
+		return invalidParameter("SetCltElement", "secondPhysicalColour", secondPhysicalColour)
+	}
+	// Set element
+	t.v.colourLookupTable[elementNumber] = colour.CltElement{
+		FirstPhysicalColour:  firstPhysicalColour,
+		FlashSpeed:           flashSpeed,
+		SecondPhysicalColour: secondPhysicalColour,
+	}
+	return nil
+}
+
+// FGetCltElement returns an element from the colour lookup table.
+// elementNumber can be in the range 0-15 for low-resolution mode, or 0-3 in high-resolution mode.
+// If any parameters are out of range, the error EInvalidParameter is given.
+// See FSetCltElement for a description of the return values.
+func (t *TGraphicsOutput) FGetCltElement(elementNumber int) (firstPhysicalColour, flashSpeed, secondPhysicalColour int) {
+	firstPhysicalColour, flashSpeed, secondPhysicalColour, err := t.GetCltElement(elementNumber)
+	t.s.setFunctionError(err)
+	return
+}
+
+// GetCltElement is the error-returning variant of FGetCltElement.
+func (t *TGraphicsOutput) GetCltElement(elementNumber int) (firstPhysicalColour, flashSpeed, secondPhysicalColour int, err error) {
+	// Validate params
+	maxElement := 15
+	if t.v.screenWidth == 80 {
+		maxElement = 3
+	}
+	if elementNumber < 0 || elementNumber > maxElement {
+		err = invalidParameter("GetCltElement", "elementNumber", elementNumber)
+		return
+	}
+	// Get element, extract values and return
+	element := t.v.colourLookupTable[elementNumber]
+	firstPhysicalColour = element.FirstPhysicalColour
+	flashSpeed = element.FlashSpeed
+	secondPhysicalColour = element.SecondPhysicalColour
+	return
+}
+
+// FSetOutputClippingAreaLimits defines a clipping area.
+// id is the clipping area id (range: 1 - 9 (0 is not user-definable))
+// minX, minY, maxX, maxY define the rectangular shape of the clipping area.
+// If any coordinates are outside the screen we get EInvalidParameter.
+// If the minima are greater than the maxima we get EInvalidParameter.
+// If id is out-of-range we get EInvalidParameter.
+func (t *TGraphicsOutput) FSetOutputClippingAreaLimits(id, minX, minY, maxX, maxY int) {
+	t.s.setFunctionError(t.SetOutputClippingAreaLimits(id, minX, minY, maxX, maxY))
+}
+
+// SetOutputClippingAreaLimits is the error-returning variant of FSetOutputClippingAreaLimits.
+func (t *TGraphicsOutput) SetOutputClippingAreaLimits(id, minX, minY, maxX, maxY int) error {
+	// Handle not on
+	if !t.On {
+		return newError("SetOutputClippingAreaLimits", errorcode.ENotInitialized)
+	}
+	// Validate params
+	limitX := 319
+	if t.v.screenWidth == 80 {
+		limitX = 639
+	}
+	if minX >= maxX {
+		return invalidParameter("SetOutputClippingAreaLimits", "minX", minX)
+	}
+	if minY >= maxY {
+		return invalidParameter("SetOutputClippingAreaLimits", "minY", minY)
+	}
+	if minX < 0 {
+		return invalidParameter("SetOutputClippingAreaLimits", "minX", minX)
+	}
+	if minY < 0 {
+		return invalidParameter("SetOutputClippingAreaLimits", "minY", minY)
+	}
+	if maxX > limitX {
+		return invalidParameter("SetOutputClippingAreaLimits", "maxX", maxX)
+	}
+	if maxY > 249 {
+		return invalidParameter("SetOutputClippingAreaLimits", "maxY", maxY)
+	}
+	if id < 1 || id > 9 {
+		return invalidParameter("SetOutputClippingAreaLimits", "id", id)
+	}
+	// Set
+	t.v.waitForEmptyDrawQueue()
+	t.v.clippingAreaTable[id] = clippingArea{
+		MinX: minX,
+		MinY: minY,
+		MaxX: maxX,
+		MaxY: maxY,
+	}
+	return nil
+}
+
+// FGetOutputClippingAreaLimits returns the limits of a clipping area.
+// id is the clipping area id (range: 1 - 9 (0 is not user-definable))
+// If id is out-of-range we get EInvalidParameter.
+// Returns the min x, min y, max x and max y co-ordinates of the clipping area boundary.
+func (t *TGraphicsOutput) FGetOutputClippingAreaLimits(id int) (minX, minY, maxX, maxY int) {
+	minX, minY, maxX, maxY, err := t.GetOutputClippingAreaLimits(id)
+	t.s.setFunctionError(err)
+	return
+}
+
+// GetOutputClippingAreaLimits is the error-returning variant of FGetOutputClippingAreaLimits.
+func (t *TGraphicsOutput) GetOutputClippingAreaLimits(id int) (minX, minY, maxX, maxY int, err error) {
+	// Handle not on
+	if !t.On {
+		err = newError("GetOutputClippingAreaLimits", errorcode.ENotInitialized)
+		return
+	}
+	// Validate params
+	if id < 1 || id > 9 {
+		err = invalidParameter("GetOutputClippingAreaLimits", "id", id)
+		return
+	}
+	// Get
+	return t.v.clippingAreaTable[id].MinX, t.v.clippingAreaTable[id].MinY, t.v.clippingAreaTable[id].MaxX, t.v.clippingAreaTable[id].MaxY, nil
+}
+
+// FSetCurrentOutputClippingArea sets the current clipping area.
+// id can be in the range 0-9.  0 is always the full screen.
+// If id is outside 0-9 the error EInvalidParameter is raised.
+func (t *TGraphicsOutput) FSetCurrentOutputClippingArea(id int) {
+	t.s.setFunctionError(t.SetCurrentOutputClippingArea(id))
+}
+
+// SetCurrentOutputClippingArea is the error-returning variant of FSetCurrentOutputClippingArea.
+func (t *TGraphicsOutput) SetCurrentOutputClippingArea(id int) error {
+	// Handle not on
+	if !t.On {
+		return newError("SetCurrentOutputClippingArea", errorcode.ENotInitialized)
+	}
+	// Validate
+	if id < 0 || id > 9 {
+		return invalidParameter("SetCurrentOutputClippingArea", "id", id)
+	}
+	// Set
+	t.v.waitForEmptyDrawQueue()
+	t.v.clippingArea = id
+	return nil
+}
+
+// FGetCurrentOutputClippingArea gets the current clipping area.
+func (t *TGraphicsOutput) FGetCurrentOutputClippingArea() (id int) {
+	id, err := t.GetCurrentOutputClippingArea()
+	t.s.setFunctionError(err)
+	return id
+}
+
+// GetCurrentOutputClippingArea is the error-returning variant of FGetCurrentOutputClippingArea.
+func (t *TGraphicsOutput) GetCurrentOutputClippingArea() (id int, err error) {
+	// Handle not on
+	if !t.On {
+		return 0, newError("GetCurrentOutputClippingArea", errorcode.ENotInitialized)
+	}
+	// Get
+	return t.v.clippingArea, nil
+}
+
+// FGetCltContents returns the entire colour lookup table.
+func FGetCltContents(t *TGraphicsOutput) (clt []int) {
+	clt, err := t.GetCltContents()
+	t.s.setFunctionError(err)
+	return clt
+}
+
+// GetCltContents is the error-returning variant of FGetCltContents.
+func (t *TGraphicsOutput) GetCltContents() (clt []int, err error) {
+	for i := 0; i < len(t.v.colourLookupTable); i++ {
+		clt = append(clt, t.v.colourLookupTable[i].FirstPhysicalColour)
+		clt = append(clt, t.v.colourLookupTable[i].FlashSpeed)
+		clt = append(clt, t.v.colourLookupTable[i].SecondPhysicalColour)
+	}
+	return clt, nil
+}
+
+// FSetNewClt replaces the entire colour lookup table with new values.
+// newClt is a list representing the new CLT.  If in low-res mode there must be 3*16 values in
+// the list, otherwise 3*4.  Each value must be in the range 0-15.
+func (t *TGraphicsOutput) FSetNewClt(newClt []int) {
+	t.s.setFunctionError(t.SetNewClt(newClt))
+}
+
+// SetNewClt is the error-returning variant of FSetNewClt.
+func (t *TGraphicsOutput) SetNewClt(newClt []int) error {
+	// Validate
+	if (t.v.screenWidth == 80 && len(newClt) != 3*4) || (t.v.screenWidth == 40 && len(newClt) != 3*16) {
+		return invalidParameter("SetNewClt", "len(newClt)", len(newClt))
+	}
+	for _, c := range newClt {
+		if c < 0 || c > 15 {
+			return invalidParameter("SetNewClt", "newClt", c)
+		}
+	}
+	// Set
+	c := 0
+	for i := 0; i < len(newClt); i = i + 3 {
+		t.v.colourLookupTable[c].FirstPhysicalColour = newClt[i]
+		t.v.colourLookupTable[c].FlashSpeed = newClt[i+1]
+		t.v.colourLookupTable[c].SecondPhysicalColour = newClt[i+2]
+		c++
+	}
+	return nil
+}
+
+// FPolyLine draws a series of conmnected lines.
+// lineStyle selects the line style to draw with and is in the range 0-6.  0 is a solid line using a dither colour,
+// 1 is a solid line using the first logical colour, 2 is dashed, 3 is dotted, 4 is dash-dotted, 5 is irregular dashed
+// and 6 is user-defined.
+// lineStyleIndex[0] selects the dither pattern to use if lineStyle=0, or defines a custom line style if lineStyle=6
+// using the entire array, e.g. []int{0, 0, 0, 0, 1, 1, 1, 1, 0, 0, 0, 0, 1, 1, 1, 1}.
+// transparency is zero if the second logical colour is to be visible, or 1 if it should be transparent.
+// The shape is drawn in XOR mode if 256 is passed as the first logical colour for dither styles or adding 256 to first
+// logical colour for all other styles.
+// TODO: use [][2]int{} for geometricData
+func (t *TGraphicsOutput) FPolyLine(lineStyle int, lineStyleIndex []int, firstLogicalColour, secondLogicalColour, transparency int, geometricData []int) {
+	t.s.setFunctionError(t.PolyLine(lineStyle, lineStyleIndex, firstLogicalColour, secondLogicalColour, transparency, geometricData))
+}
+
+// PolyLine is the error-returning variant of FPolyLine.
+func (t *TGraphicsOutput) PolyLine(lineStyle int, lineStyleIndex []int, firstLogicalColour, secondLogicalColour, transparency int, geometricData []int) error {
+	// Handle not on
+	if !t.On {
+		return newError("PolyLine", errorcode.ENotInitialized)
+	}
+	// Validate
+	if len(geometricData) < 2 {
+		return invalidParameter("PolyLine", "len(geometricData)", len(geometricData))
+	}
+	if len(geometricData)%2 != 0 {
+		return invalidParameter("PolyLine", "len(geometricData)", len(geometricData))
+	}
+	xor, firstLogicalColour, err := t.validateLineStyle("PolyLine", lineStyle, lineStyleIndex, firstLogicalColour, secondLogicalColour, transparency)
+	if err != nil {
+		return err
+	}
+	// Convert into slice of Coord
+	coords := []coord{}
+	for i := 0; i < len(geometricData)-1; i += 2 {
+		coords = append(coords, coord{X: geometricData[i], Y: geometricData[i+1]})
+	}
+	// Draw lines on array
+	width, height, offsetX, offsetY := determineFeatureSize(coords)
+	img := make2darray.Make2dArray(width, height, -1)
+	for i := 0; i < len(coords)-1; i++ {
+		fromXY := coords[i]
+		toXY := coords[i]
+		if i != len(coords)-1 {
+			toXY = coords[i+1]
+		}
+		img = t.v.drawLine(img, fromXY.X-offsetX, fromXY.Y-offsetY, toXY.X-offsetX, toXY.Y-offsetY, lineStyle, lineStyleIndex, firstLogicalColour, secondLogicalColour, transparency)
+	}
+	// Load array into a feature and draw it
+	t.v.drawFeature(feature{pixels: img, x: offsetX, y: offsetY, colour: -1, xor: xor}) // colour=-1 because we have a colour feature with transparent (-1) background
+	return nil
+}
+
+// validateLineStyle checks the line style parameters shared by PolyLine and
+// ArcOfEllipse.  It returns the first logical colour with XOR mode taken out of it.
+func (t *TGraphicsOutput) validateLineStyle(function string, lineStyle int, lineStyleIndex []int, firstLogicalColour, secondLogicalColour, transparency int) (xor bool, first int, err error) {
+	if lineStyle < 0 || lineStyle > 6 {
+		return false, 0, invalidParameter(function, "lineStyle", lineStyle)
+	}
+	if lineStyle == 0 {
+		// Must have dith pattern selected in lineStyleIndex[0]
+		if len(lineStyleIndex) != 1 {
+			return false, 0, invalidParameter(function, "len(lineStyleIndex)", len(lineStyleIndex))
+		}
+		if lineStyleIndex[0] < 0 || lineStyleIndex[0] > 15 {
+			return false, 0, invalidParameter(function, "lineStyleIndex[0]", lineStyleIndex[0])
+		}
+	}
+	if lineStyle == 6 {
+		// Must have dith pattern defined in lineStyleIndex[0:15]
+		if len(lineStyleIndex) != 16 {
+			return false, 0, invalidParameter(function, "len(lineStyleIndex)", len(lineStyleIndex))
+		}
+		for _, i := range lineStyleIndex {
+			maxC := 15
+			if t.v.screenWidth == 80 {
+				maxC = 3
+			}
+			if i < 0 || i > maxC {
+				return false, 0, invalidParameter(function, "lineStyleIndex", i)
+			}
+		}
+	}
+	if transparency < 0 || transparency > 1 {
+		return false, 0, invalidParameter(function, "transparency", transparency)
+	}
+	maxCol := 15
+	if t.v.screenWidth == 80 {
+		maxCol = 3
+	}
+	if firstLogicalColour < 0 || (firstLogicalColour > maxCol && firstLogicalColour < 256) || (firstLogicalColour > 256+maxCol) {
+		return false, 0, invalidParameter(function, "firstLogicalColour", firstLogicalColour)
+	}
+	if secondLogicalColour < 0 || secondLogicalColour > maxCol {
+		return false, 0, invalidParameter(function, "secondLogicalColour", secondLogicalColour)
+	}
+	// XOR mode?
+	if lineStyle == 0 && firstLogicalColour == 256 {
+		xor = true
+	}
+	if lineStyle > 0 && firstLogicalColour > 255 {
+		xor = true
+		firstLogicalColour = firstLogicalColour - 256
+	}
+	return xor, firstLogicalColour, nil
+}
+
+// FFillArea fills the area described by a set of vertices given in the geometricData parameter.
+// fillStyle: 0 - hollow, 1 - solid using fillColour1, 2 - solid using dither, 3 - solid using hatched pattern.
+// fillStyleIndex (fillStyle=0, 1) - ignored.
+// fillStyleIndex (fillStyle=2) - dither pattern to use.
+// fillStyleIndex (fillStyle=3) - hatching pattern to use.
+// fillColour1 - the first fill colour
+// fillColour2 - the second fill colour (ignored unless hatching selected).
+// transparency - fillColour2 is transparent if set to 1 in hatching mode.
+// XOR mode can be selected by passing 256 in fillColour1 when using a dither pattern,
+// or by adding 256 to fillColour1's value if otherwise.
+// geometricData... TODO: use [][2]int{} for geometricData
+func (t *TGraphicsOutput) FFillArea(fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency int, geometricData []int) {
+	t.s.setFunctionError(t.FillArea(fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, geometricData))
+}
+
+// FillArea is the error-returning variant of FFillArea.
+func (t *TGraphicsOutput) FillArea(fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency int, geometricData []int) error {
+	return t.fillArea("FillArea", fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, geometricData)
+}
+
+// fillArea does the work for FillArea and anything else that boils down to a
+// filled polygon.  function is the name to blame in any error.
+func (t *TGraphicsOutput) fillArea(function string, fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency int, geometricData []int) error {
+	// Handle not on
+	if !t.On {
+		return newError(function, errorcode.ENotInitialized)
+	}
+	f, err := t.fillAreaFeature(function, fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, geometricData)
+	if err != nil {
+		return err
+	}
+	t.v.drawFeature(f)
+	return nil
+}
+
+// fillAreaFeature validates the parameters for fillArea and returns the filled
+// polygon as a feature without drawing it, so it can be added to first.
+func (t *TGraphicsOutput) fillAreaFeature(function string, fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency int, geometricData []int) (feature, error) {
+	// Validate
+	if len(geometricData) < 2 {
+		return feature{}, invalidParameter(function, "len(geometricData)", len(geometricData))
+	}
+	if len(geometricData)%2 != 0 {
+		return feature{}, invalidParameter(function, "len(geometricData)", len(geometricData))
+	}
+	if transparency < 0 || transparency > 1 {
+		return feature{}, invalidParameter(function, "transparency", transparency)
+	}
+	maxCol := 15
+	if t.v.screenWidth == 80 {
+		maxCol = 3
+	}
+	if fillColour1 < 0 || (fillColour1 > maxCol && fillColour1 < 256) || (fillColour1 > 256+maxCol) {
+		return feature{}, invalidParameter(function, "fillColour1", fillColour1)
+	}
+	if fillColour2 < 0 || fillColour2 > maxCol {
+		return feature{}, invalidParameter(function, "fillColour2", fillColour2)
+	}
+	// XOR mode ?
+	xor := false
+	if fillColour1 > 255 {
+		xor = true
+		fillColour1 = fillColour1 - 256
+	}
+	if fillStyle == 2 && fillColour1 == 256 {
+		xor = true
+	}
+	// Convert into slice of Coord
+	coords := []coord{}
+	for i := 0; i < len(geometricData)-1; i += 2 {
+		coords = append(coords, coord{X: geometricData[i], Y: geometricData[i+1]})
+	}
+	// Prepare an array to draw on
+	width, height, offsetX, offsetY := determineFeatureSize(coords)
+	img := make2darray.Make2dArray(width, height, -1)
+	// Close the shape if necessary
+	if coords[0].X != coords[len(coords)-1].X || coords[0].Y != coords[len(coords)-1].Y {
+		coords = append(coords, coord{coords[0].X, coords[0].Y})
+	}
+	// Draw outline
+	for i := 0; i < len(coords)-1; i++ {
+		img = t.v.drawLine(img, coords[i].X-offsetX, coords[i].Y-offsetY, coords[i+1].X-offsetX, coords[i+1].Y-offsetY, 1, []int{0}, fillColour1, fillColour2, transparency)
+	}
+	// If hollow shape then we're already done
+	if fillStyle == 0 {
+		return feature{pixels: img, x: offsetX, y: offsetY, colour: -1, xor: xor}, nil
+	}
+	// Otherwise let's cheat and use draw2d to draw a filled polygon
+	img = t.v.d2dFilledPolygon(geometricData, width, height, offsetX, offsetY, fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency)
+	return feature{pixels: img, x: offsetX, y: offsetY, colour: -1, xor: xor}, nil
+}
+
+// FFloodFillArea fills the screen out from the point (x, y) to a boundary.
+// fillStyle: 0 or 1 - solid using fillColour1, 2 - solid using dither, 3 - solid using hatched pattern.
+// fillStyleIndex (fillStyle=0, 1) - ignored.
+// fillStyleIndex (fillStyle=2) - dither pattern to use.
+// fillStyleIndex (fillStyle=3) - hatching pattern to use.
+// fillColour1 - the first fill colour.
+// fillColour2 - the second fill colour (ignored unless hatching selected).
+// transparency - of fillColour2.
+// boundarySpecification: 0 - any colour different from that of the seed position is a boundary, 1 - the boundary is any pixel of colour colourOfBoundary.
+// colourOfBoundary - the colour of the boundary (ignored if boundarySpecification == 0).
+// x - x-coordinate of the seed position.
+// y - y-coordinate of the seed position.
+func (t *TGraphicsOutput) FFloodFillArea(fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, boundarySpecification, colourOfBoundary, x, y int) {
+	t.s.setFunctionError(t.FloodFillArea(fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, boundarySpecification, colourOfBoundary, x, y))
+}
+
+// FloodFillArea is the error-returning variant of FFloodFillArea.
+func (t *TGraphicsOutput) FloodFillArea(fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, boundarySpecification, colourOfBoundary, x, y int) error {
+	// Handle not on
+	if !t.On {
+		return newError("FloodFillArea", errorcode.ENotInitialized)
+	}
+	// Validate
+	if transparency < 0 || transparency > 1 {
+		return invalidParameter("FloodFillArea", "transparency", transparency)
+	}
+	maxCol := 15
+	if t.v.screenWidth == 80 {
+		maxCol = 3
+	}
+	if fillColour1 < 0 || fillColour1 > maxCol {
+		return invalidParameter("FloodFillArea", "fillColour1", fillColour1)
+	}
+	if fillColour2 < 0 || fillColour2 > maxCol {
+		return invalidParameter("FloodFillArea", "fillColour2", fillColour2)
+	}
+	if fillStyleIndex == 0 {
+		fillStyleIndex = 1
+	}
+	if boundarySpecification < 0 || boundarySpecification > 1 {
+		return invalidParameter("FloodFillArea", "boundarySpecification", boundarySpecification)
+	}
+	if colourOfBoundary < 0 || colourOfBoundary > maxCol {
+		return invalidParameter("FloodFillArea", "colourOfBoundary", colourOfBoundary)
+	}
+	t.v.waitForEmptyDrawQueue()
+	t.v.floodFill(fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, boundarySpecification, colourOfBoundary, x, y)
+	return nil
+}
+
+// FPolymarker draws a series of unconnected markers on the screen.
+// markerStyle: (1-6) the style of marker, 6 being a custom marker defined in markerShape.
+// markerSizeX: (1-50) the x magnification of the marker.
+// markerSizeY: (1-50) the y magnification of the marker.
+// logicalColour: the logical colour of the marker.
+// markerShape: the shape of a custom marker if markerStyle==6, otherwise ignored.
+func (t *TGraphicsOutput) FPolymarker(markerStyle, markerSizeX, markerSizeY, logicalColour int, markerShape [][2]int, geometricData [][2]int) {
+	t.s.setFunctionError(t.Polymarker(markerStyle, markerSizeX, markerSizeY, logicalColour, markerShape, geometricData))
+}
+
+// Polymarker is the error-returning variant of FPolymarker.
+func (t *TGraphicsOutput) Polymarker(markerStyle, markerSizeX, markerSizeY, logicalColour int, markerShape [][2]int, geometricData [][2]int) error {
+	// Handle not on
+	if !t.On {
+		return newError("Polymarker", errorcode.ENotInitialized)
+	}
+	// Validate
+	if markerStyle < 1 || markerStyle > 6 {
+		return invalidParameter("Polymarker", "markerStyle", markerStyle)
+	}
+	if markerSizeX < 1 || markerSizeX > 50 {
+		return invalidParameter("Polymarker", "markerSizeX", markerSizeX)
+	}
+	if markerSizeY < 1 || markerSizeY > 50 {
+		return invalidParameter("Polymarker", "markerSizeY", markerSizeY)
+	}
+	maxCol := 15
+	if t.v.screenWidth == 80 {
+		maxCol = 3
+	}
+	if logicalColour < 0 || (logicalColour > maxCol && logicalColour < 256) || (logicalColour > 256+maxCol) {
+		return invalidParameter("Polymarker", "logicalColour", logicalColour)
+	}
+	// XOR mode ?
+	xor := false
+	if logicalColour > 255 {
+		xor = true
+		logicalColour = logicalColour - 256
+	}
+	// Handle custom marker
+	if markerStyle != 6 {
+		markerShape = t.v.polymarkers[markerStyle-1]
+	}
+	// Create an array to draw the marker on (find out how big it needs to be first)
+	maxX := 0
+	maxY := 0
+	for _, p := range markerShape {
+		if (p[0] == -128 && p[1] == 0) || (p[0] == -128 && p[1] == -128) {
+			// Ignore pen-up / end
+			continue
+		}
+		absX := int(math.Abs(float64(p[0])))
+		absY := int(math.Abs(float64(p[1])))
+		if absX > maxX {
+			maxX = absX
+		}
+		if absY > maxY {
+			maxY = absY
+		}
+	}
+	sizeX := (markerSizeX * 2 * maxX) + 1
+	sizeY := (markerSizeY * 2 * maxY) + 1
+	img := make2darray.Make2dArray(sizeX, sizeY, -1)
+	// Draw polymarker in img
+	penUp := true
+	x, y := 0, 0
+	offsetX, offsetY := sizeX/2, sizeY/2
+	for _, p := range markerShape {
+		// Set penUp if requested
+		if p[0] == -128 && p[1] == 0 {
+			penUp = true
+			continue
+		}
+		// Break if end of marker
+		if p[0] == -128 && p[1] == -128 {
+			break
+		}
+		// Move-to, if pen is up
+		if penUp {
+			x, y = markerSizeX*p[0], markerSizeY*p[1]
+			penUp = false
+			continue
+		}
+		// Otherwise draw-to
+		img = t.v.drawLine(img, x+offsetX, y+offsetY, (markerSizeX*p[0])+offsetX, (markerSizeY*p[1])+offsetY, 1, []int{0}, logicalColour, 0, 0)
+		x, y = markerSizeX*p[0], markerSizeY*p[1]
+	}
+	// Render imgs on screen
+	for _, p := range geometricData {
+		t.v.drawFeature(feature{pixels: img, x: p[0] - offsetX, y: p[1] - offsetY, colour: -1, xor: xor})
+	}
+	return nil
+}
+
+// FPlotCharacterString plots a character string on the screen.
+// orientation: (0-3) the direction to plot, rotated 90 degress anticlockwise each time.
+// yMagnification: (1-50) the amount to enlarge vertically.
+// xMagnification: (1-50) the amount to enlarge horizontally.
+// logicalColour: the colour to plot in, add 256 to plot in XOR mode.
+// font: 0 - use the standard character set, 1 - use the alternative character set (custom charset not yet implemented)
+// chars: the string of chars to plot.
+// x, y: The co-ordinates to plot at.
+func (t *TGraphicsOutput) FPlotCharacterString(orientation, yMagnification, xMagnification, logicalColour, font int, chars string, x, y int) {
+	t.s.setFunctionError(t.PlotCharacterString(orientation, yMagnification, xMagnification, logicalColour, font, chars, x, y))
+}
+
+// PlotCharacterString is the error-returning variant of FPlotCharacterString.
+func (t *TGraphicsOutput) PlotCharacterString(orientation, yMagnification, xMagnification, logicalColour, font int, chars string, x, y int) error {
+	// Handle not on
+	if !t.On {
+		return newError("PlotCharacterString", errorcode.ENotInitialized)
+	}
+	// Validate
+	if yMagnification < 1 || yMagnification > 50 {
+		return invalidParameter("PlotCharacterString", "yMagnification", yMagnification)
+	}
+	if xMagnification < 1 || xMagnification > 50 {
+		return invalidParameter("PlotCharacterString", "xMagnification", xMagnification)
+	}
+	if font < 0 || font > 1 {
+		return invalidParameter("PlotCharacterString", "font", font)
+	}
+	if orientation < 0 || orientation > 3 {
+		return invalidParameter("PlotCharacterString", "orientation", orientation)
+	}
+	maxCol := 15
+	if t.v.screenWidth == 80 {
+		maxCol = 3
+	}
+	if logicalColour < 0 || (logicalColour > maxCol && logicalColour < 256) || (logicalColour > 256+maxCol) {
+		return invalidParameter("PlotCharacterString", "logicalColour", logicalColour)
+	}
+	// XOR mode ?
+	xor := false
+	if logicalColour > 255 {
+		xor = true
+		logicalColour = logicalColour - 256
+	}
+	// Plot chars and applying scaling/direction
+	imgWidth := len(chars) * 8
+	imgHeight := 10
+	img := make2darray.Make2dArray(imgWidth, imgHeight, -1)
+	// Select charset and draw chars on image
+	xOffset := 0
+	for _, c := range chars {
+		// draw char on image
+		var charPixels [][]int
+		switch font {
+		case 0:
+			charPixels = t.v.charSet0[c]
+		case 1:
+			charPixels = t.v.charSet1[c]
+		}
+		for x := 0; x < 8; x++ {
+			for y := 0; y < 10; y++ {
+				img[y][x+xOffset] = charPixels[y][x]
+			}
+		}
+		xOffset += 8
+	}
+	resizedFeature := t.v.resizeFeature(feature{pixels: img, x: x, y: y, colour: logicalColour, xor: xor}, imgWidth*xMagnification, imgHeight*yMagnification)
+	rotatedFeature := t.v.rotateFeature(feature{pixels: resizedFeature.pixels, x: x, y: y, colour: logicalColour, xor: xor}, orientation)
+	// Correct x, y for orientation
+	if orientation == 2 {
+		rotatedFeature.x = rotatedFeature.x - ((len(chars) - 1) * 8 * xMagnification)
+	}
+	if orientation == 3 {
+		rotatedFeature.y = rotatedFeature.y - ((len(chars) - 1) * 8 * yMagnification)
+	}
+	t.v.drawFeature(rotatedFeature)
+	return nil
+}
+
+// FDrawSprite draws a sprite on the screen and stores the overwritten data in an saveTable array.
+func (t *TGraphicsOutput) FDrawSprite(s sprite.Sprite, saveTable *sprite.SaveTable, x, y, pose int, xor bool, clippingAreaId int) {
+	t.s.setFunctionError(t.DrawSprite(s, saveTable, x, y, pose, xor, clippingAreaId))
+}
+
+// DrawSprite is the error-returning variant of FDrawSprite.
+func (t *TGraphicsOutput) DrawSprite(s sprite.Sprite, saveTable *sprite.SaveTable, x, y, pose int, xor bool, clippingAreaId int) error {
+	// Handle not on
+	if !t.On {
+		return newError("DrawSprite", errorcode.ENotInitialized)
+	}
+	// Validate
+	if pose >= len(s.Poses) {
+		return invalidParameter("DrawSprite", "pose", pose)
+	}
+	maxPoses := 2
+	if t.v.screenWidth == 40 {
+		maxPoses = 4
+	}
+	if len(s.Poses) > maxPoses {
+		return invalidParameter("DrawSprite", "len(s.Poses)", len(s.Poses))
+	}
+	if clippingAreaId < 0 || clippingAreaId >= 10 {
+		return invalidParameter("DrawSprite", "clippingAreaId", clippingAreaId)
+	}
+	// Do it
+	t.v.drawFeature(feature{
+		pixels:                      s.Poses[pose],
+		x:                           x - s.Hotspot[0],
+		y:                           y - s.Hotspot[1],
+		colour:                      -1,
+		xor:                         xor,
+		isSprite:                    true,
+		isDrawSprite:                true,
+		saveTable:                   saveTable,
+		overrideCurrentClippingArea: true,
+		clippingArea:                clippingAreaId,
+	})
+	return nil
+}
+
+// FMoveSprite moves an existing sprite on the screen and stores the overwritten data in an saveTable array.
+// Because of the way saveTable has been implemented, this command does not require the "old x, y" as originally
+// documented.
+func (t *TGraphicsOutput) FMoveSprite(s sprite.Sprite, saveTable *sprite.SaveTable, x, y, pose int, xor bool, clippingAreaId int) {
+	t.s.setFunctionError(t.MoveSprite(s, saveTable, x, y, pose, xor, clippingAreaId))
+}
+
+// MoveSprite is the error-returning variant of FMoveSprite.
+func (t *TGraphicsOutput) MoveSprite(s sprite.Sprite, saveTable *sprite.SaveTable, x, y, pose int, xor bool, clippingAreaId int) error {
+	// Handle not on
+	if !t.On {
+		return newError("MoveSprite", errorcode.ENotInitialized)
+	}
+	// Validate
+	if pose >= len(s.Poses) {
+		return invalidParameter("MoveSprite", "pose", pose)
+	}
+	maxPoses := 2
+	if t.v.screenWidth == 40 {
+		maxPoses = 4
+	}
+	if len(s.Poses) > maxPoses {
+		return invalidParameter("MoveSprite", "len(s.Poses)", len(s.Poses))
+	}
+	if clippingAreaId < 0 || clippingAreaId >= 10 {
+		return invalidParameter("MoveSprite", "clippingAreaId", clippingAreaId)
+	}
+	t.v.drawFeature(feature{
+		pixels:                      s.Poses[pose],
+		x:                           x - s.Hotspot[0],
+		y:                           y - s.Hotspot[1],
+		colour:                      -1,
+		xor:                         xor,
+		isSprite:                    true,
+		isMoveSprite:                true,
+		saveTable:                   saveTable,
+		overrideCurrentClippingArea: true,
+		clippingArea:                clippingAreaId,
+	})
+	return nil
+}
+
+// FEraseSprite erases a sprite associated with a saveTable.  Unlike in the original implementation, it is only necessary
+// to pass the sprite and saveTable as arguments.
+func (t *TGraphicsOutput) FEraseSprite(saveTable *sprite.SaveTable) {
+	t.s.setFunctionError(t.EraseSprite(saveTable))
+}
+
+// EraseSprite is the error-returning variant of FEraseSprite.
+func (t *TGraphicsOutput) EraseSprite(saveTable *sprite.SaveTable) error {
+	// Handle not on
+	if !t.On {
+		return newError("EraseSprite", errorcode.ENotInitialized)
+	}
+	pixels := [][]int{{}} // only need an empty pose because we're not going to draw anything
+	t.v.drawFeature(feature{
+		pixels:       pixels,
+		x:            0,
+		y:            0,
+		colour:       -1,
+		isSprite:     true,
+		isMoveSprite: true,
+		saveTable:    saveTable,
+	})
+	return nil
+}
+
+// FPlonkLogo draws the RM Nimbus logo on the screen, starting with the
+// bottom-left at x, y.
+func (t *TGraphicsOutput) FPlonkLogo(x, y int) {
+	t.s.setFunctionError(t.PlonkLogo(x, y))
+}
+
+// PlonkLogo is the error-returning variant of FPlonkLogo.
+func (t *TGraphicsOutput) PlonkLogo(x, y int) error {
+	// Handle not on
+	if !t.On {
+		return newError("PlonkLogo", errorcode.ENotInitialized)
+	}
+	f := feature{pixels: t.v.logo, x: x, y: y, colour: -1, xor: false}
+	t.v.drawFeature(f)
+	return nil
+}
+
+// FPieSlice draws pie slices and filled circles.  xCentre and yCentre represent the position of the
+// centre of the circle or pie slice.  Radius is the radius along the vertical axis (this is because
+// in high-resolution mode the circle must be stretched across the horizontal axis so it is still a
+// circle).  theta1 and theta2 are the starting and stopping angles of the slice, respectively.  If
+// theta1 == theta2 then a complete circle will be drawn.  theta1 and theta2 are measured in thousandths
+// of a radian, with 0 or 6283 being vertically up (don't ask me, this is how it was originally) and
+// 3142 being vertically down.  colour is the colour of the circle (outline and fill colour).
+// For fill styles, outlines and XOR mode use FStyledPieSlice instead.
+func (t *TGraphicsOutput) FPieSlice(xCentre, yCentre, radius, theta1, theta2, colour int) {
+	t.s.setFunctionError(t.PieSlice(xCentre, yCentre, radius, theta1, theta2, colour))
+}
+
+// PieSlice is the error-returning variant of FPieSlice.
+func (t *TGraphicsOutput) PieSlice(xCentre, yCentre, radius, theta1, theta2, colour int) error {
+	// Handle not on
+	if !t.On {
+		return newError("PieSlice", errorcode.ENotInitialized)
+	}
+	img := make2darray.Make2dArray((radius*2)+1, (radius*2)+1, -1)
+	t.v.drawCircle(img, radius, radius, radius, theta1, theta2, colour)
+	f := feature{pixels: img, x: xCentre - radius, y: yCentre - radius, colour: -1, xor: false}
+	if t.v.screenWidth == 80 {
+		rescaledF := t.v.resizeFeature(f, len(img[0])*2, len(img))
+		rescaledF.x = xCentre - (2 * radius)
+		t.v.drawFeature(rescaledF) // Draw a rescaled feature if in high-resolution mode
+	} else {
+		t.v.drawFeature(f) // Otherwise draw as-is
+	}
+	return nil
+}
+
+// FStyledPieSlice draws a pie slice or circle like FPieSlice, but filled and outlined however you like.
+// The fill parameters are the same as FFillArea's: fillStyle 0 is outline only (in fillColour1), 1 is
+// solid, 2 is dithered and 3 is hatched, and XOR mode is selected in fillColour1.  The outline is then
+// drawn over the top using the same line parameters as FPolyLine, with the line style carrying on all
+// the way round the edge, or pass -1 in lineStyle to leave the outline out.  If the fill and the outline
+// are both in XOR mode they are drawn as a single shape, so drawing the same slice twice rubs it out.
+// The centre, radius and angles are the same as FPieSlice's.
+func (t *TGraphicsOutput) FStyledPieSlice(fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, lineStyle int, lineStyleIndex []int, lineColour1, lineColour2, lineTransparency, xCentre, yCentre, radius, theta1, theta2 int) {
+	t.s.setFunctionError(t.StyledPieSlice(fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, lineStyle, lineStyleIndex, lineColour1, lineColour2, lineTransparency, xCentre, yCentre, radius, theta1, theta2))
+}
+
+// StyledPieSlice is the error-returning variant of FStyledPieSlice.
+func (t *TGraphicsOutput) StyledPieSlice(fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, lineStyle int, lineStyleIndex []int, lineColour1, lineColour2, lineTransparency, xCentre, yCentre, radius, theta1, theta2 int) error {
+	// Handle not on
+	if !t.On {
+		return newError("StyledPieSlice", errorcode.ENotInitialized)
+	}
+	// Validate
+	if err := validateEllipse("StyledPieSlice", radius, radius, theta1, theta2); err != nil {
+		return err
+	}
+	lineXor := false
+	if lineStyle != -1 {
+		var err error
+		lineXor, lineColour1, err = t.validateLineStyle("StyledPieSlice", lineStyle, lineStyleIndex, lineColour1, lineColour2, lineTransparency)
+		if err != nil {
+			return err
+		}
+	}
+	// Work out the edge of the circle, stretching it across in high-resolution mode
+	xRadius := radius
+	if t.v.screenWidth == 80 {
+		xRadius = radius * 2
+	}
+	whole := theta1 == theta2
+	arc := ellipseArc(xCentre, yCentre, xRadius, radius, float64(theta1)/1000.0, float64(theta2)/1000.0, whole)
+	// Fill it
+	geometricData := []int{}
+	if !whole {
+		geometricData = append(geometricData, xCentre, yCentre)
+	}
+	for _, c := range arc {
+		geometricData = append(geometricData, c.X, c.Y)
+	}
+	fill, err := t.fillAreaFeature("StyledPieSlice", fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, geometricData)
+	if err != nil {
+		return err
+	}
+	if lineStyle == -1 {
+		t.v.drawFeature(fill)
+		return nil
+	}
+	// Go round the edge, in and out of the centre if it's a slice
+	edge := []coord{}
+	if whole {
+		edge = append(edge, arc...)
+		edge = append(edge, arc[0])
+	} else {
+		edge = append(edge, linePoints(xCentre, yCentre, arc[0].X, arc[0].Y)...)
+		edge = append(edge, arc[1:]...)
+		edge = append(edge, linePoints(arc[len(arc)-1].X, arc[len(arc)-1].Y, xCentre, yCentre)[1:]...)
+	}
+	for i := range edge {
+		edge[i].X -= fill.x
+		edge[i].Y -= fill.y
+	}
+	outline := make2darray.Make2dArray(len(fill.pixels[0]), len(fill.pixels), -1)
+	outline = t.v.drawPoints(outline, edge, lineStyle, lineStyleIndex, lineColour1, lineColour2, lineTransparency)
+	// Draw it all in one go if we can, otherwise fill first then outline
+	if lineXor != fill.xor {
+		t.v.drawFeature(fill)
+		t.v.drawFeature(feature{pixels: outline, x: fill.x, y: fill.y, colour: -1, xor: lineXor})
+		return nil
+	}
+	for y := range outline {
+		for x := range outline[y] {
+			if outline[y][x] >= 0 {
+				fill.pixels[y][x] = outline[y][x]
+			}
+		}
+	}
+	t.v.drawFeature(fill)
+	return nil
+}
+
+// FArcOfEllipse draws an arc of an ellipse.  xCentre and yCentre are the centre of the ellipse,
+// and xRadius and yRadius its radii along each axis in pixels (unlike FPieSlice nothing gets stretched
+// in high-resolution mode, so if you want a circle pass twice the radius in xRadius).  theta1 and
+// theta2 are the starting and stopping angles of the arc, measured clockwise in thousandths of a
+// radian with 0 being vertically up, just like FPieSlice.  If theta1 == theta2 then the whole ellipse
+// is drawn.  The line style parameters are the same as FPolyLine's, including XOR mode, and the line
+// style carries on smoothly all the way round the arc.
+func (t *TGraphicsOutput) FArcOfEllipse(lineStyle int, lineStyleIndex []int, firstLogicalColour, secondLogicalColour, transparency, xCentre, yCentre, xRadius, yRadius, theta1, theta2 int) {
+	t.s.setFunctionError(t.ArcOfEllipse(lineStyle, lineStyleIndex, firstLogicalColour, secondLogicalColour, transparency, xCentre, yCentre, xRadius, yRadius, theta1, theta2))
+}
+
+// ArcOfEllipse is the error-returning variant of FArcOfEllipse.
+func (t *TGraphicsOutput) ArcOfEllipse(lineStyle int, lineStyleIndex []int, firstLogicalColour, secondLogicalColour, transparency, xCentre, yCentre, xRadius, yRadius, theta1, theta2 int) error {
+	// Handle not on
+	if !t.On {
+		return newError("ArcOfEllipse", errorcode.ENotInitialized)
+	}
+	// Validate
+	if err := validateEllipse("ArcOfEllipse", xRadius, yRadius, theta1, theta2); err != nil {
+		return err
+	}
+	xor, firstLogicalColour, err := t.validateLineStyle("ArcOfEllipse", lineStyle, lineStyleIndex, firstLogicalColour, secondLogicalColour, transparency)
+	if err != nil {
+		return err
+	}
+	// Draw the arc on an array
+	coords := ellipsePoints(xCentre, yCentre, xRadius, yRadius, theta1, theta2)
+	width, height, offsetX, offsetY := determineFeatureSize(coords)
+	img := make2darray.Make2dArray(width, height, -1)
+	for i := range coords {
+		coords[i].X -= offsetX
+		coords[i].Y -= offsetY
+	}
+	img = t.v.drawPoints(img, coords, lineStyle, lineStyleIndex, firstLogicalColour, secondLogicalColour, transparency)
+	// Load array into a feature and draw it
+	t.v.drawFeature(feature{pixels: img, x: offsetX, y: offsetY, colour: -1, xor: xor})
+	return nil
+}
+
+// FEllipseSlice draws a filled ellipse, or a sector of one if theta1 != theta2.  The centre, radii and
+// angles are the same as FArcOfEllipse's and the fill parameters are the same as FFillArea's, so
+// dither patterns, hatching and XOR mode all work.
+func (t *TGraphicsOutput) FEllipseSlice(fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, xCentre, yCentre, xRadius, yRadius, theta1, theta2 int) {
+	t.s.setFunctionError(t.EllipseSlice(fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, xCentre, yCentre, xRadius, yRadius, theta1, theta2))
+}
+
+// EllipseSlice is the error-returning variant of FEllipseSlice.
+func (t *TGraphicsOutput) EllipseSlice(fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, xCentre, yCentre, xRadius, yRadius, theta1, theta2 int) error {
+	// Handle not on
+	if !t.On {
+		return newError("EllipseSlice", errorcode.ENotInitialized)
+	}
+	if err := validateEllipse("EllipseSlice", xRadius, yRadius, theta1, theta2); err != nil {
+		return err
+	}
+	// A sector is a polygon from the centre round the arc and back again, a whole
+	// ellipse is just the arc.
+	geometricData := []int{}
+	if theta1 != theta2 {
+		geometricData = append(geometricData, xCentre, yCentre)
+	}
+	for _, c := range ellipsePoints(xCentre, yCentre, xRadius, yRadius, theta1, theta2) {
+		geometricData = append(geometricData, c.X, c.Y)
+	}
+	return t.fillArea("EllipseSlice", fillStyle, fillStyleIndex, fillColour1, fillColour2, transparency, geometricData)
+}
+
+// validateEllipse checks the radii and angles of an ellipse.
+func validateEllipse(function string, xRadius, yRadius, theta1, theta2 int) error {
+	if xRadius < 0 {
+		return invalidParameter(function, "xRadius", xRadius)
+	}
+	if yRadius < 0 {
+		return invalidParameter(function, "yRadius", yRadius)
+	}
+	if theta1 < 0 || theta1 > 6283 {
+		return invalidParameter(function, "theta1", theta1)
+	}
+	if theta2 < 0 || theta2 > 6283 {
+		return invalidParameter(function, "theta2", theta2)
+	}
+	return nil
+}
+
+// FSetDitherPattern sets one of the user-definable dither patterns.  ditherId is the number
+// of the dither pattern to be defined (8-15). ditherPattern is the new dither pattern defined
+// in an array, e.g. [4][4]int{{1,2,1,2},{2,1,2,1},{1,2,1,2},{2,1,2,1}}.  Note that the maximum
+// colour value in the dither is set according to screen mode, i.e. 3 in high-resolution mode
+// and 15 in low-resolution mode.
+func (t *TGraphicsOutput) FSetDitherPattern(ditherId int, ditherPattern [4][4]int) {
+	t.s.setFunctionError(t.SetDitherPattern(ditherId, ditherPattern))
+}
+
+// SetDitherPattern is the error-returning variant of FSetDitherPattern.
+func (t *TGraphicsOutput) SetDitherPattern(ditherId int, ditherPattern [4][4]int) error {
+	// Handle not on
+	if !t.On {
+		return newError("SetDitherPattern", errorcode.ENotInitialized)
+	}
+	maxColour := 3
+	if t.v.screenWidth == 40 {
+		maxColour = 15
+	}
+	if ditherId < 8 || ditherId > 15 {
+		return invalidParameter("SetDitherPattern", "ditherId", ditherId)
+	}
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			colour := ditherPattern[y][x]
+			if colour > maxColour || colour < 0 {
+				return invalidParameter("SetDitherPattern", "ditherPattern", colour)
+			} else {
+				t.v.ditherPatterns[ditherId][y][x] = colour
+			}
+		}
+	}
+	t.v.initDitherLookupTables()
+	return nil
+}
+
+// FGetDitherPattern returns a dither pattern.
+func (t *TGraphicsOutput) FGetDitherPattern(ditherId int) (ditherPattern [4][4]int) {
+	ditherPattern, err := t.GetDitherPattern(ditherId)
+	t.s.setFunctionError(err)
+	return ditherPattern
+}
+
+// GetDitherPattern is the error-returning variant of FGetDitherPattern.
+func (t *TGraphicsOutput) GetDitherPattern(ditherId int) (ditherPattern [4][4]int, err error) {
+	// Handle not on
+	if !t.On {
+		err = newError("GetDitherPattern", errorcode.ENotInitialized)
+		return
+	}
+	if ditherId < 8 || ditherId > 15 {
+		err = invalidParameter("GetDitherPattern", "ditherId", ditherId)
+		return
+	}
+	ditherPattern = t.v.ditherPatterns[ditherId] // This *should* be copy by reference
+	return ditherPattern, nil
+}
+
+// FSetHatchingPattern sets one of the user-definable dither patterns.  hatching is the number
+// of the hacching pattern to be defined (0-5). hatchingPattern is the new hatching pattern defined
+// in an array, e.g. [16][16]int{{1,1,..,0,0},..,{1,1,..,0,0}}.  Note that hatching patterns do
+// not store implicit colour information and so the allowed values are either 0 or 1.
+func (t *TGraphicsOutput) FSetHatchingPattern(hatchingId int, hatchingPattern [16][16]int) {
+	t.s.setFunctionError(t.SetHatchingPattern(hatchingId, hatchingPattern))
+}
+
+// SetHatchingPattern is the error-returning variant of FSetHatchingPattern.
+func (t *TGraphicsOutput) SetHatchingPattern(hatchingId int, hatchingPattern [16][16]int) error {
+	// Handle not on
+	if !t.On {
+		return newError("SetHatchingPattern", errorcode.ENotInitialized)
+	}
+	if hatchingId < 0 || hatchingId > 5 {
+		return invalidParameter("SetHatchingPattern", "hatchingId", hatchingId)
+	}
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 16; y++ {
+			colour := hatchingPattern[y][x]
+			if colour > 1 || colour < 0 {
+				return invalidParameter("SetHatchingPattern", "hatchingPattern", colour)
+			} else {
+				t.v.hatchingPatterns[hatchingId][y][x] = colour
+			}
+		}
+	}
+	t.v.initHatchingLookupTables()
+	return nil
+}
+
+// FGetHatchingPattern returns a hatching pattern.
+func (t *TGraphicsOutput) FGetHatchingPattern(hatchingId int) (hatchingPattern [16][16]int) {
+	hatchingPattern, err := t.GetHatchingPattern(hatchingId)
+	t.s.setFunctionError(err)
+	return hatchingPattern
+}
+
+// GetHatchingPattern is the error-returning variant of FGetHatchingPattern.
+func (t *TGraphicsOutput) GetHatchingPattern(hatchingId int) (hatchingPattern [16][16]int, err error) {
+	// Handle not on
+	if !t.On {
+		err = newError("GetHatchingPattern", errorcode.ENotInitialized)
+		return
+	}
+	if hatchingId < 0 || hatchingId > 5 {
+		err = invalidParameter("GetHatchingPattern", "hatchingId", hatchingId)
+		return
+	}
+	hatchingPattern = t.v.hatchingPatterns[hatchingId] // This *should* be copy by reference
+	return hatchingPattern, nil
+}
+
+// FGetDisplayLine is not implemented as it's redundant in nimgobus.
+func (t *TGraphicsOutput) FGetDisplayLine() {
+	t.s.setFunctionError(t.GetDisplayLine())
+}
+
+// GetDisplayLine is the error-returning variant of FGetDisplayLine.
+func (t *TGraphicsOutput) GetDisplayLine() error {
+	return newError("GetDisplayLine", errorcode.EFuncNotImplemented)
+}
+
+// FReadPixel returns the logical colour of a pixel on the screen.
+func (t *TGraphicsOutput) FReadPixel(x, y int) (colour int) {
+	colour, err := t.ReadPixel(x, y)
+	t.s.setFunctionError(err)
+	return colour
+}
+
+// ReadPixel is the error-returning variant of FReadPixel.
+func (t *TGraphicsOutput) ReadPixel(x, y int) (colour int, err error) {
+	// Handle not on
+	if !t.On {
+		return 0, newError("ReadPixel", errorcode.ENotInitialized)
+	}
+	maxX := 319
+	if t.v.screenWidth == 80 {
+		maxX = 639
+	}
+	if x < 0 || x > maxX {
+		return 0, invalidParameter("ReadPixel", "x", x)
+	}
+	if y < 0 || y > 249 {
+		return 0, invalidParameter("ReadPixel", "y", y)
+	}
+	t.v.waitForEmptyDrawQueue()
+	t.v.muDrawQueue.Lock()
+	t.v.muMemory.Lock()
+	colour = t.v.memory[249-y][x]
+	t.v.muMemory.Unlock()
+	t.v.muDrawQueue.Unlock()
+	return colour, nil
+}
+
+// FReadToLimit scans along a row of the screen from (x, y) until it reaches a boundary or the edge of
+// the screen, and returns the x co-ordinate of the last pixel before the boundary.  This is the bit you
+// need to write your own fill tools: scan left then right and fill everything from one limit to the
+// other, inclusive.
+// direction: 0 - scan left, 1 - scan right.
+// boundarySpecification: 0 - any colour different from that of (x, y) is a boundary, 1 - the boundary
+// is any pixel of colour colourOfBoundary.
+// colourOfBoundary - the colour of the boundary (ignored if boundarySpecification == 0).
+// The starting pixel itself is never a boundary, so the limit is x if the next pixel along is one.
+// atEdge is true if the scan ran off the edge of the screen rather than finding a boundary.
+func (t *TGraphicsOutput) FReadToLimit(x, y, direction, boundarySpecification, colourOfBoundary int) (limit int, atEdge bool) {
+	limit, atEdge, err := t.ReadToLimit(x, y, direction, boundarySpecification, colourOfBoundary)
+	t.s.setFunctionError(err)
+	return limit, atEdge
+}
+
+// ReadToLimit is the error-returning variant of FReadToLimit.
+func (t *TGraphicsOutput) ReadToLimit(x, y, direction, boundarySpecification, colourOfBoundary int) (limit int, atEdge bool, err error) {
+	// Handle not on
+	if !t.On {
+		return 0, false, newError("ReadToLimit", errorcode.ENotInitialized)
+	}
+	// Validate
+	maxX := 319
+	maxCol := 15
+	if t.v.screenWidth == 80 {
+		maxX = 639
+		maxCol = 3
+	}
+	if x < 0 || x > maxX {
+		return 0, false, invalidParameter("ReadToLimit", "x", x)
+	}
+	if y < 0 || y > 249 {
+		return 0, false, invalidParameter("ReadToLimit", "y", y)
+	}
+	if direction < 0 || direction > 1 {
+		return 0, false, invalidParameter("ReadToLimit", "direction", direction)
+	}
+	if boundarySpecification < 0 || boundarySpecification > 1 {
+		return 0, false, invalidParameter("ReadToLimit", "boundarySpecification", boundarySpecification)
+	}
+	if colourOfBoundary < 0 || colourOfBoundary > maxCol {
+		return 0, false, invalidParameter("ReadToLimit", "colourOfBoundary", colourOfBoundary)
+	}
+	step := -1
+	if direction == 1 {
+		step = 1
+	}
+	// Scan
+	t.v.waitForEmptyDrawQueue()
+	t.v.muDrawQueue.Lock()
+	t.v.muMemory.Lock()
+	defer t.v.muDrawQueue.Unlock()
+	defer t.v.muMemory.Unlock()
+	row := &t.v.memory[249-y]
+	seedColour := row[x]
+	for limit = x; ; limit += step {
+		next := limit + step
+		if next < 0 || next > maxX {
+			return limit, true, nil
+		}
+		if boundarySpecification == 0 && row[next] != seedColour {
+			return limit, false, nil
+		}
+		if boundarySpecification == 1 && row[next] == colourOfBoundary {
+			return limit, false, nil
+		}
+	}
+}
+
+// FReadAreaWord is not implemented because it's redundant in nimgobus.
+func (t *TGraphicsOutput) FReadAreaWord() {
+	t.s.setFunctionError(t.ReadAreaWord())
+}
+
+// ReadAreaWord is the error-returning variant of FReadAreaWord.
+func (t *TGraphicsOutput) ReadAreaWord() error {
+	return newError("ReadAreaWord", errorcode.EFuncNotImplemented)
+}
+
+// FWriteAreaWord is not implemented because it's redundant in nimgobus.
+func (t *TGraphicsOutput) FWriteAreaWord() {
+	t.s.setFunctionError(t.WriteAreaWord())
+}
+
+// WriteAreaWord is the error-returning variant of FWriteAreaWord.
+func (t *TGraphicsOutput) WriteAreaWord() error {
+	return newError("WriteAreaWord", errorcode.EFuncNotImplemented)
+}
+
+// FCopyAreaWord is not implemented because it's redundant in nimgobus.
+func (t *TGraphicsOutput) FCopyAreaWord() {
+	t.s.setFunctionError(t.CopyAreaWord())
+}
+
+// CopyAreaWord is the error-returning variant of FCopyAreaWord.
+func (t *TGraphicsOutput) CopyAreaWord() error {
+	return newError("CopyAreaWord", errorcode.EFuncNotImplemented)
+}
+
+// FSwapAreaWord is not implemented because it's redundant in nimgobus.
+func (t *TGraphicsOutput) FSwapAreaWord() {
+	t.s.setFunctionError(t.SwapAreaWord())
+}
+
+// SwapAreaWord is the error-returning variant of FSwapAreaWord.
+func (t *TGraphicsOutput) SwapAreaWord() error {
+	return newError("SwapAreaWord", errorcode.EFuncNotImplemented)
+}
+
+// FIsine is not implemented because it's redundant in nimgobus.
+func (t *TGraphicsOutput) FIsine() {
+	t.s.setFunctionError(t.Isine())
+}
+
+// Isine is the error-returning variant of FIsine.
+func (t *TGraphicsOutput) Isine() error {
+	return newError("Isine", errorcode.EFuncNotImplemented)
+}
+
+// FIcos is not implemented because it's redundant in nimgobus.
+func (t *TGraphicsOutput) FIcos() {
+	t.s.setFunctionError(t.Icos())
+}
+
+// Icos is the error-returning variant of FIcos.
+func (t *TGraphicsOutput) Icos() error {
+	return newError("Icos", errorcode.EFuncNotImplemented)
+}
+
+// FReadAreaPixel returns the screen memory from a given area of the screen.
+func (t *TGraphicsOutput) FReadAreaPixel(xMin, yMin, xMax, yMax int) (img [][]int) {
+	img, err := t.ReadAreaPixel(xMin, yMin, xMax, yMax)
+	t.s.setFunctionError(err)
+	return img
+}
+
+// ReadAreaPixel is the error-returning variant of FReadAreaPixel.
+func (t *TGraphicsOutput) ReadAreaPixel(xMin, yMin, xMax, yMax int) (img [][]int, err error) {
+	// Handle not on
+	if !t.On {
+		return nil, newError("ReadAreaPixel", errorcode.ENotInitialized)
+	}
+	// Validate params
+	xLimit := 320
+	if t.v.screenWidth == 80 {
+		xLimit = 640
+	}
+	if xMin < 0 || xMin > xLimit {
+		return nil, invalidParameter("ReadAreaPixel", "xMin", xMin)
+	}
+	if yMin < 0 || yMin > 249 {
+		return nil, invalidParameter("ReadAreaPixel", "yMin", yMin)
+	}
+	if xMax < 0 || xMax > xLimit {
+		return nil, invalidParameter("ReadAreaPixel", "xMax", xMax)
+	}
+	if yMax < 0 || yMax > 249 {
+		return nil, invalidParameter("ReadAreaPixel", "yMax", yMax)
+	}
+	if xMin > xMax {
+		return nil, invalidParameter("ReadAreaPixel", "xMin", xMin)
+	}
+	if yMin > yMax {
+		return nil, invalidParameter("ReadAreaPixel", "yMin", yMin)
+	}
+	// Capture screen memory
+	t.v.waitForEmptyDrawQueue()
+	t.v.muDrawQueue.Lock()
+	t.v.muMemory.Lock()
+	for y := yMax; y >= yMin; y-- {
+		column := []int{}
+		for x := xMin; x <= xMax; x++ {
+			column = append(column, t.v.memory[249-y][x])
+		}
+		img = append(img, column)
+	}
+	t.v.muMemory.Unlock()
+	t.v.muDrawQueue.Unlock()
+	return img, nil
+}
+
+// FWriteAreaPixel writes an image array captured by FReadAreaPixel to the
+// screen.
+func (t *TGraphicsOutput) FWriteAreaPixel(img [][]int, xMin, yMin int, xor bool, ignoreLogicalColour int) {
+	t.s.setFunctionError(t.WriteAreaPixel(img, xMin, yMin, xor, ignoreLogicalColour))
+}
+
+// WriteAreaPixel is the error-returning variant of FWriteAreaPixel.
+func (t *TGraphicsOutput) WriteAreaPixel(img [][]int, xMin, yMin int, xor bool, ignoreLogicalColour int) error {
+	// Handle not on
+	if !t.On {
+		return newError("WriteAreaPixel", errorcode.ENotInitialized)
+	}
+	// Handle ignoreLogicalColour
+	maxColour := 3
+	if t.v.screenWidth == 80 {
+		maxColour = 15
+	}
+	if ignoreLogicalColour >= 0 && ignoreLogicalColour <= maxColour {
+		for y := 0; y < len(img); y++ {
+			for x := 0; x < len(img[0]); x++ {
+				if img[y][x] == ignoreLogicalColour {
+					img[y][x] = -1
+				}
+			}
+		}
+	}
+	// Draw it
+	t.v.waitForEmptyDrawQueue()
+	f := feature{pixels: img, x: xMin, y: yMin, xor: xor, colour: -1}
+	t.v.drawFeature(f)
+	return nil
+}
+
+// FCopyAreaPixel copies the area of the screen from (xMin, yMin) to (xMax, yMax) so its bottom-left
+// corner is at (xDest, yDest), without the round trip of FReadAreaPixel and FWriteAreaPixel.  The
+// source and destination can overlap, so it's handy for scrolling and dragging things about.  If xor
+// is true the area is XORed onto the destination, and pixels of ignoreLogicalColour are not copied
+// (pass -1 to copy everything).  The destination is clipped by the current clipping area.
+func (t *TGraphicsOutput) FCopyAreaPixel(xMin, yMin, xMax, yMax, xDest, yDest int, xor bool, ignoreLogicalColour int) {
+	t.s.setFunctionError(t.CopyAreaPixel(xMin, yMin, xMax, yMax, xDest, yDest, xor, ignoreLogicalColour))
+}
+
+// CopyAreaPixel is the error-returning variant of FCopyAreaPixel.
+func (t *TGraphicsOutput) CopyAreaPixel(xMin, yMin, xMax, yMax, xDest, yDest int, xor bool, ignoreLogicalColour int) error {
+	// Handle not on
+	if !t.On {
+		return newError("CopyAreaPixel", errorcode.ENotInitialized)
+	}
+	// Validate params
+	xLimit := 319
+	maxColour := 15
+	if t.v.screenWidth == 80 {
+		xLimit = 639
+		maxColour = 3
+	}
+	if xMin < 0 || xMin > xLimit {
+		return invalidParameter("CopyAreaPixel", "xMin", xMin)
+	}
+	if yMin < 0 || yMin > 249 {
+		return invalidParameter("CopyAreaPixel", "yMin", yMin)
+	}
+	if xMax < xMin || xMax > xLimit {
+		return invalidParameter("CopyAreaPixel", "xMax", xMax)
+	}
+	if yMax < yMin || yMax > 249 {
+		return invalidParameter("CopyAreaPixel", "yMax", yMax)
+	}
+	if ignoreLogicalColour < -1 || ignoreLogicalColour > maxColour {
+		return invalidParameter("CopyAreaPixel", "ignoreLogicalColour", ignoreLogicalColour)
+	}
+	// Queue it up like anything else that's drawn
+	t.v.drawFeature(feature{areaCopy: &areaCopy{
+		xMin: xMin, yMin: yMin, xMax: xMax, yMax: yMax,
+		xDest: xDest, yDest: yDest,
+		xor: xor, ignoreLogicalColour: ignoreLogicalColour,
+	}})
+	return nil
+}
@@ -0,0 +1,101 @@
+package subbios
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSetNewClt(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	// Hi-res mode has 4 elements
+	clt := []int{1, 0, 2, 3, 1, 4, 5, 2, 6, 7, 0, 8}
+	if err := s.TGraphicsOutput.SetNewClt(clt); err != nil {
+		t.Fatalf("SetNewClt(%v) unexpected error: %v", clt, err)
+	}
+	got, err := s.TGraphicsOutput.GetCltContents()
+	if err != nil {
+		t.Fatalf("GetCltContents() unexpected error: %v", err)
+	}
+	if len(got) < len(clt) || !reflect.DeepEqual(got[:len(clt)], clt) {
+		t.Errorf("GetCltContents() = %v, expected it to start with %v", got, clt)
+	}
+	first, flash, second, _ := s.TGraphicsOutput.GetCltElement(2)
+	if first != 5 || flash != 2 || second != 6 {
+		t.Errorf("GetCltElement(2) = %d, %d, %d, expected 5, 2, 6", first, flash, second)
+	}
+}
+
+func TestReadPixel(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	s.TGraphicsOutput.v.muMemory.Lock()
+	s.TGraphicsOutput.v.memory[249][0] = 1
+	s.TGraphicsOutput.v.memory[0][639] = 2
+	s.TGraphicsOutput.v.memory[249-10][20] = 3
+	s.TGraphicsOutput.v.muMemory.Unlock()
+
+	tests := []struct {
+		x, y, colour int
+	}{
+		{0, 0, 1},
+		{639, 249, 2},
+		{20, 10, 3},
+		{20, 11, 0},
+	}
+	for _, tt := range tests {
+		colour, err := s.TGraphicsOutput.ReadPixel(tt.x, tt.y)
+		if err != nil {
+			t.Fatalf("ReadPixel(%d, %d) unexpected error: %v", tt.x, tt.y, err)
+		}
+		if colour != tt.colour {
+			t.Errorf("ReadPixel(%d, %d) = %d, expected %d", tt.x, tt.y, colour, tt.colour)
+		}
+	}
+	for _, xy := range [][2]int{{640, 0}, {0, 250}, {-1, 0}} {
+		if _, err := s.TGraphicsOutput.ReadPixel(xy[0], xy[1]); !errors.Is(err, ErrInvalidParameter) {
+			t.Errorf("ReadPixel(%d, %d) returned error %v, expected %v", xy[0], xy[1], err, ErrInvalidParameter)
+		}
+	}
+}
+
+func TestReadToLimit(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	s.Stdio.Printf("\x1b~F") // Hide cursor
+	// A row of 0s with a 1 at 100-109 and a 2 at 200
+	s.TGraphicsOutput.FPolyLine(1, []int{0}, 1, 0, 0, []int{100, 50, 109, 50})
+	s.TGraphicsOutput.FPolyLine(1, []int{0}, 2, 0, 0, []int{200, 50, 200, 50})
+
+	tests := []struct {
+		x, direction, spec, boundary int
+		limit                        int
+		atEdge                       bool
+	}{
+		{50, 0, 0, 0, 0, true},
+		{50, 1, 0, 0, 99, false},
+		{105, 0, 0, 0, 100, false},
+		{105, 1, 0, 0, 109, false},
+		{150, 1, 0, 0, 199, false},
+		{50, 1, 1, 2, 199, false},
+		{250, 1, 1, 2, 639, true},
+		{250, 0, 1, 2, 201, false},
+		{99, 1, 0, 0, 99, false},
+	}
+	for _, tt := range tests {
+		limit, atEdge, err := s.TGraphicsOutput.ReadToLimit(tt.x, 50, tt.direction, tt.spec, tt.boundary)
+		if err != nil {
+			t.Fatalf("ReadToLimit(%d, 50, %d, %d, %d) unexpected error: %v", tt.x, tt.direction, tt.spec, tt.boundary, err)
+		}
+		if limit != tt.limit || atEdge != tt.atEdge {
+			t.Errorf("ReadToLimit(%d, 50, %d, %d, %d) = %d, %v; want %d, %v", tt.x, tt.direction, tt.spec, tt.boundary, limit, atEdge, tt.limit, tt.atEdge)
+		}
+	}
+	if _, _, err := s.TGraphicsOutput.ReadToLimit(640, 50, 0, 0, 0); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("ReadToLimit(640, ...) error = %v, want ErrInvalidParameter", err)
+	}
+}
@@ -0,0 +1,297 @@
+package subbios
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// THardSums has all the t_hard_maths functions attached to it.
+// Each F* function reports failure by setting Subbios.FunctionError, and has an
+// error-returning variant of the same name without the F prefix.
+type THardSums struct {
+	s *Subbios
+}
+
+// setResult sets FunctionError and FunctionStatus for the register-style functions.
+func (t *THardSums) setResult(err error) {
+	t.s.setFunctionError(err)
+	t.s.FunctionStatus = 0
+}
+
+// FAddTwoReals adds "a" to "b" and returns the result.
+func (t *THardSums) FAddTwoReals(a, b float64) float64 {
+	r, err := t.AddTwoReals(a, b)
+	t.setResult(err)
+	return r
+}
+
+// AddTwoReals is the error-returning variant of FAddTwoReals.
+func (t *THardSums) AddTwoReals(a, b float64) (float64, error) {
+	return a + b, nil
+}
+
+// FSubtractReals subtracts "a" from "b" and returns the result.
+func (t *THardSums) FSubtractReals(a, b float64) float64 {
+	r, err := t.SubtractReals(a, b)
+	t.setResult(err)
+	return r
+}
+
+// SubtractReals is the error-returning variant of FSubtractReals.
+func (t *THardSums) SubtractReals(a, b float64) (float64, error) {
+	return b - a, nil
+}
+
+// FMultiplyReals multiplies "a" by "b" and returns the result.
+func (t *THardSums) FMultiplyReals(a, b float64) float64 {
+	r, err := t.MultiplyReals(a, b)
+	t.setResult(err)
+	return r
+}
+
+// MultiplyReals is the error-returning variant of FMultiplyReals.
+func (t *THardSums) MultiplyReals(a, b float64) (float64, error) {
+	return a * b, nil
+}
+
+// FDivideReals divides "b" by "a" and returns the result.
+func (t *THardSums) FDivideReals(a, b float64) float64 {
+	r, err := t.DivideReals(a, b)
+	t.setResult(err)
+	return r
+}
+
+// DivideReals is the error-returning variant of FDivideReals.
+func (t *THardSums) DivideReals(a, b float64) (float64, error) {
+	// Returns 0, ENotANumber if a=0.
+	if a == 0 {
+		return 0, notANumber("DivideReals", "a", a)
+	}
+	return b / a, nil
+}
+
+// FTruncateReal returns the integer part of "a" as a float.
+func (t *THardSums) FTruncateReal(a float64) float64 {
+	r, err := t.TruncateReal(a)
+	t.setResult(err)
+	return r
+}
+
+// TruncateReal is the error-returning variant of FTruncateReal.
+func (t *THardSums) TruncateReal(a float64) (float64, error) {
+	if a < 0 {
+		return -1.0 * math.Floor(a*-1.0), nil
+	}
+	return math.Floor(a), nil
+}
+
+// FRealFromInt converts an integer to floating point.
+func (t *THardSums) FRealFromInt(a int) float64 {
+	r, err := t.RealFromInt(a)
+	t.setResult(err)
+	return r
+}
+
+// RealFromInt is the error-returning variant of FRealFromInt.
+func (t *THardSums) RealFromInt(a int) (float64, error) {
+	return float64(a), nil
+}
+
+// FIntLessThanReal basically floors a real and returns an int.
+func (t *THardSums) FIntLessThanReal(a float64) int {
+	r, err := t.IntLessThanReal(a)
+	t.setResult(err)
+	return r
+}
+
+// IntLessThanReal is the error-returning variant of FIntLessThanReal.
+func (t *THardSums) IntLessThanReal(a float64) (int, error) {
+	return int(math.Floor(a)), nil
+}
+
+// FIntPartOfReal returns the integer part of "a" as an int.
+func (t *THardSums) FIntPartOfReal(a float64) int {
+	r, err := t.IntPartOfReal(a)
+	t.setResult(err)
+	return r
+}
+
+// IntPartOfReal is the error-returning variant of FIntPartOfReal.
+func (t *THardSums) IntPartOfReal(a float64) (int, error) {
+	if a < 0 {
+		return int(-1.0 * math.Floor(a*-1.0)), nil
+	}
+	return int(math.Floor(a)), nil
+}
+
+// FCommonLog returns the log to base 10 of "a".
+func (t *THardSums) FCommonLog(a float64) float64 {
+	r, err := t.CommonLog(a)
+	t.setResult(err)
+	return r
+}
+
+// CommonLog is the error-returning variant of FCommonLog.
+func (t *THardSums) CommonLog(a float64) (float64, error) {
+	if a <= 0 {
+		return 0, notANumber("CommonLog", "a", a)
+	}
+	return math.Log10(a), nil
+}
+
+// FNaturalLog returns the natural log of "a".
+func (t *THardSums) FNaturalLog(a float64) float64 {
+	r, err := t.NaturalLog(a)
+	t.setResult(err)
+	return r
+}
+
+// NaturalLog is the error-returning variant of FNaturalLog.
+func (t *THardSums) NaturalLog(a float64) (float64, error) {
+	if a <= 0 {
+		return 0, notANumber("NaturalLog", "a", a)
+	}
+	return math.Log(a), nil
+}
+
+// FInverseNaturalLog returns the inverse natural log of "a".  Note
+// that this function uses 2.7182 as the base, as described in the
+// Nimbus SUBBIOS documentation.
+func (t *THardSums) FInverseNaturalLog(a float64) float64 {
+	r, err := t.InverseNaturalLog(a)
+	t.setResult(err)
+	return r
+}
+
+// InverseNaturalLog is the error-returning variant of FInverseNaturalLog.
+func (t *THardSums) InverseNaturalLog(a float64) (float64, error) {
+	if a <= 0 {
+		return 0, notANumber("InverseNaturalLog", "a", a)
+	}
+	r := math.Pow(2.7182, a)
+	if math.IsNaN(r) {
+		return 0, notANumber("InverseNaturalLog", "a", a)
+	}
+	return r, nil
+}
+
+// FRaiseToPower raises a float to an integer power.
+func (t *THardSums) FRaiseToPower(a float64, b int) float64 {
+	r, err := t.RaiseToPower(a, b)
+	t.setResult(err)
+	return r
+}
+
+// RaiseToPower is the error-returning variant of FRaiseToPower.
+func (t *THardSums) RaiseToPower(a float64, b int) (float64, error) {
+	return math.Pow(a, float64(b)), nil
+}
+
+// FSquareRoot returns the square root of "a".
+func (t *THardSums) FSquareRoot(a float64) float64 {
+	r, err := t.SquareRoot(a)
+	t.setResult(err)
+	return r
+}
+
+// SquareRoot is the error-returning variant of FSquareRoot.
+func (t *THardSums) SquareRoot(a float64) (float64, error) {
+	r := math.Sqrt(a)
+	if math.IsNaN(r) {
+		return 0, notANumber("SquareRoot", "a", a)
+	}
+	return r, nil
+}
+
+// FCosine returns the cosine of "a".
+func (t *THardSums) FCosine(a float64) float64 {
+	r, err := t.Cosine(a)
+	t.setResult(err)
+	return r
+}
+
+// Cosine is the error-returning variant of FCosine.
+func (t *THardSums) Cosine(a float64) (float64, error) {
+	r := math.Cos(a)
+	if math.IsNaN(r) {
+		return 0, notANumber("Cosine", "a", a)
+	}
+	return r, nil
+}
+
+// FSine returns the sine of "a".
+func (t *THardSums) FSine(a float64) float64 {
+	r, err := t.Sine(a)
+	t.setResult(err)
+	return r
+}
+
+// Sine is the error-returning variant of FSine.
+func (t *THardSums) Sine(a float64) (float64, error) {
+	r := math.Sin(a)
+	if math.IsNaN(r) {
+		return 0, notANumber("Sine", "a", a)
+	}
+	return r, nil
+}
+
+// FTangent returns the tangent of "a".
+func (t *THardSums) FTangent(a float64) float64 {
+	r, err := t.Tangent(a)
+	t.setResult(err)
+	return r
+}
+
+// Tangent is the error-returning variant of FTangent.
+func (t *THardSums) Tangent(a float64) (float64, error) {
+	r := math.Tan(a)
+	if math.IsNaN(r) {
+		return 0, notANumber("Tangent", "a", a)
+	}
+	return r, nil
+}
+
+// FArctan returns the inverse tangent of "a".
+func (t *THardSums) FArctan(a float64) float64 {
+	r, err := t.Arctan(a)
+	t.setResult(err)
+	return r
+}
+
+// Arctan is the error-returning variant of FArctan.
+func (t *THardSums) Arctan(a float64) (float64, error) {
+	r := math.Atan(a)
+	if math.IsNaN(r) {
+		return 0, notANumber("Arctan", "a", a)
+	}
+	return r, nil
+}
+
+// FRealToAscii returns the string representation of "a".
+func (t *THardSums) FRealToAscii(a float64) string {
+	r, err := t.RealToAscii(a)
+	t.setResult(err)
+	return r
+}
+
+// RealToAscii is the error-returning variant of FRealToAscii.
+func (t *THardSums) RealToAscii(a float64) (string, error) {
+	return fmt.Sprintf("%g", a), nil
+}
+
+// FAsciiToReal converts the string representation of a float to float.
+func (t *THardSums) FAsciiToReal(a string) float64 {
+	r, err := t.AsciiToReal(a)
+	t.setResult(err)
+	return r
+}
+
+// AsciiToReal is the error-returning variant of FAsciiToReal.
+func (t *THardSums) AsciiToReal(a string) (float64, error) {
+	r, err := strconv.ParseFloat(a, 64)
+	if err != nil {
+		return 0, notANumber("AsciiToReal", "a", a)
+	}
+	return r, nil
+}
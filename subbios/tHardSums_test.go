@@ -1,9 +1,10 @@
 package subbios
 
 import (
+	"errors"
 	"testing"
 
-	"github.com/adamstimb/nimgobus/internal/subbios/errorcode"
+	"github.com/adamstimb/nimgobus/subbios/errorcode"
 )
 
 func TestFAddTwoReals(t *testing.T) {
@@ -19,6 +20,7 @@ func TestFAddTwoReals(t *testing.T) {
 
 	s := Subbios{}
 	s.Init()
+	t.Cleanup(s.Close)
 
 	for _, tt := range tests {
 		result := s.THardSums.FAddTwoReals(tt.a, tt.b)
@@ -41,6 +43,7 @@ func TestFSubtractReals(t *testing.T) {
 
 	s := Subbios{}
 	s.Init()
+	t.Cleanup(s.Close)
 
 	for _, tt := range tests {
 		result := s.THardSums.FSubtractReals(tt.a, tt.b)
@@ -63,6 +66,7 @@ func TestFMultiplyReals(t *testing.T) {
 
 	s := Subbios{}
 	s.Init()
+	t.Cleanup(s.Close)
 
 	for _, tt := range tests {
 		result := s.THardSums.FMultiplyReals(tt.a, tt.b)
@@ -79,12 +83,14 @@ func TestFDivideReals(t *testing.T) {
 		r float64
 		e int
 	}{
-		{2.0, 0.0, 0, errorcode.ENotANumber},
+		{0.0, 5.0, 0, errorcode.ENotANumber},
+		{2.0, 0.0, 0, errorcode.EOk},
 		{2.0, 8.8, 4.4, errorcode.EOk},
 	}
 
 	s := Subbios{}
 	s.Init()
+	t.Cleanup(s.Close)
 
 	for _, tt := range tests {
 		result := s.THardSums.FDivideReals(tt.a, tt.b)
@@ -112,6 +118,7 @@ func TestFTruncateReal(t *testing.T) {
 
 	s := Subbios{}
 	s.Init()
+	t.Cleanup(s.Close)
 
 	for _, tt := range tests {
 		result := s.THardSums.FTruncateReal(tt.a)
@@ -132,6 +139,7 @@ func TestFRealFromInt(t *testing.T) {
 
 	s := Subbios{}
 	s.Init()
+	t.Cleanup(s.Close)
 
 	for _, tt := range tests {
 		result := s.THardSums.FRealFromInt(tt.a)
@@ -154,6 +162,7 @@ func TestFIntLessThanReal(t *testing.T) {
 
 	s := Subbios{}
 	s.Init()
+	t.Cleanup(s.Close)
 
 	for _, tt := range tests {
 		result := s.THardSums.FIntLessThanReal(tt.a)
@@ -178,6 +187,7 @@ func TestFIntPartOfReal(t *testing.T) {
 
 	s := Subbios{}
 	s.Init()
+	t.Cleanup(s.Close)
 
 	for _, tt := range tests {
 		result := s.THardSums.FIntPartOfReal(tt.a)
@@ -201,6 +211,7 @@ func TestFCommonLog(t *testing.T) {
 
 	s := Subbios{}
 	s.Init()
+	t.Cleanup(s.Close)
 
 	for _, tt := range tests {
 		result := s.THardSums.FCommonLog(tt.a)
@@ -225,6 +236,7 @@ func TestFNaturalLog(t *testing.T) {
 
 	s := Subbios{}
 	s.Init()
+	t.Cleanup(s.Close)
 
 	for _, tt := range tests {
 		result := s.THardSums.FNaturalLog(tt.a)
@@ -250,6 +262,7 @@ func TestFRealToAscii(t *testing.T) {
 
 	s := Subbios{}
 	s.Init()
+	t.Cleanup(s.Close)
 
 	for _, tt := range tests {
 		result := s.THardSums.FRealToAscii(tt.a)
@@ -276,6 +289,7 @@ func TestFAsciiToReal(t *testing.T) {
 
 	s := Subbios{}
 	s.Init()
+	t.Cleanup(s.Close)
 
 	for _, tt := range tests {
 		result := s.THardSums.FAsciiToReal(tt.a)
@@ -287,3 +301,22 @@ func TestFAsciiToReal(t *testing.T) {
 		}
 	}
 }
+
+func TestDivideRealsError(t *testing.T) {
+	var th THardSums
+	_, err := th.DivideReals(0.0, 5.0)
+	if !errors.Is(err, ErrNotANumber) {
+		t.Errorf("DivideReals(0.0, 5.0) returned error %v, expected %v", err, ErrNotANumber)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Function != "DivideReals" || e.Param != "a" || e.Value != 0.0 {
+		t.Errorf("DivideReals(0.0, 5.0) returned error %#v, expected *Error from DivideReals blaming a=0", err)
+	}
+	if _, err := th.DivideReals(2.0, 8.8); err != nil {
+		t.Errorf("DivideReals(2.0, 8.8) returned error %v, expected nil", err)
+	}
+	_, err = th.SquareRoot(-1)
+	if !errors.As(err, &e) || e.Function != "SquareRoot" || e.Param != "a" || e.Value != -1.0 {
+		t.Errorf("SquareRoot(-1) returned error %#v, expected *Error from SquareRoot blaming a=-1", err)
+	}
+}
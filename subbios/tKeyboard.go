@@ -0,0 +1,177 @@
+package subbios
+
+import (
+	"sync"
+	"time"
+)
+
+// KeyAction is what happened to a key in a KeyboardEvent.
+type KeyAction int
+
+// Key actions.
+const (
+	KeyPressed  KeyAction = iota // The key went down
+	KeyReleased                  // The key came up
+	KeyRepeated                  // The key is being held down and has auto-repeated
+)
+
+// KeyOther is the Key of a KeyboardEvent for a char typed on a key the keyboard driver
+// doesn't know about, e.g. punctuation, or when it can't tell which key typed it.
+const KeyOther Key = -1
+
+// maxKeyboardEvents is how many events TKeyboard keeps before it starts dropping the
+// oldest ones.
+const maxKeyboardEvents = 256
+
+// KeyboardEvent is something that happened to a key.
+type KeyboardEvent struct {
+	Action    KeyAction
+	Key       Key      // Which key it was
+	ScanCode  int      // The PC (set 1) scan code of the key, or 0 for KeyOther
+	Rune      rune     // The char the key typed, if any (not when it's released)
+	Modifiers Modifier // The modifiers held down at the time
+}
+
+// TKeyboard is a keyboard driver that tells you about keys going down and coming up,
+// unlike Stdio which only gets the chars and key codes typed.  It's safe to use from
+// any goroutine, so apps running in their own goroutine can poll it.
+type TKeyboard struct {
+	s        *Subbios
+	muEvents sync.Mutex
+	events   []KeyboardEvent
+	held     [keyCount]bool
+	mods     Modifier
+}
+
+// scanCodes are the PC (set 1) scan codes of the keys.  The extended keys (cursor
+// keys and friends) have an E0 prefix on a real PC, which is left off here.
+var scanCodes = [keyCount]int{
+	KeyEnter: 0x1c, KeyBackspace: 0x0e, KeyLeft: 0x4b, KeyRight: 0x4d, KeyUp: 0x48, KeyDown: 0x50,
+	KeyHome: 0x47, KeyEnd: 0x4f, KeyTab: 0x0f,
+	KeyF1: 0x3b, KeyF2: 0x3c, KeyF3: 0x3d, KeyF4: 0x3e, KeyF5: 0x3f, KeyF6: 0x40,
+	KeyF7: 0x41, KeyF8: 0x42, KeyF9: 0x43, KeyF10: 0x44, KeyF11: 0x57, KeyF12: 0x58,
+	KeyControl: 0x1d, KeyShift: 0x2a, KeyScrollLock: 0x46, KeyEscape: 0x01,
+	KeyPageUp: 0x49, KeyPageDown: 0x51, KeyInsert: 0x52, KeyDelete: 0x53, KeyAlt: 0x38, KeySpace: 0x39,
+	KeyQ: 0x10, KeyW: 0x11, KeyE: 0x12, KeyR: 0x13, KeyT: 0x14, KeyY: 0x15, KeyU: 0x16, KeyI: 0x17, KeyO: 0x18, KeyP: 0x19,
+	KeyA: 0x1e, KeyS: 0x1f, KeyD: 0x20, KeyF: 0x21, KeyG: 0x22, KeyH: 0x23, KeyJ: 0x24, KeyK: 0x25, KeyL: 0x26,
+	KeyZ: 0x2c, KeyX: 0x2d, KeyC: 0x2e, KeyV: 0x2f, KeyB: 0x30, KeyN: 0x31, KeyM: 0x32,
+	Key1: 0x02, Key2: 0x03, Key3: 0x04, Key4: 0x05, Key5: 0x06, Key6: 0x07, Key7: 0x08, Key8: 0x09, Key9: 0x0a, Key0: 0x0b,
+}
+
+// NextEvent returns the oldest keyboard event and true, or false if there aren't any.
+func (t *TKeyboard) NextEvent() (KeyboardEvent, bool) {
+	t.muEvents.Lock()
+	defer t.muEvents.Unlock()
+	if len(t.events) == 0 {
+		return KeyboardEvent{}, false
+	}
+	e := t.events[0]
+	t.events = t.events[1:]
+	return e, true
+}
+
+// WaitEvent waits for a keyboard event and returns it.
+func (t *TKeyboard) WaitEvent() KeyboardEvent {
+	for {
+		if e, ok := t.NextEvent(); ok {
+			return e
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// FlushEvents throws away any keyboard events that haven't been read.
+func (t *TKeyboard) FlushEvents() {
+	t.muEvents.Lock()
+	defer t.muEvents.Unlock()
+	t.events = nil
+}
+
+// IsKeyHeld returns true if key is being held down.
+func (t *TKeyboard) IsKeyHeld(key Key) bool {
+	t.muEvents.Lock()
+	defer t.muEvents.Unlock()
+	if key < 0 || key >= keyCount {
+		return false
+	}
+	return t.held[key]
+}
+
+// Modifiers returns the modifier keys that are being held down.
+func (t *TKeyboard) Modifiers() Modifier {
+	t.muEvents.Lock()
+	defer t.muEvents.Unlock()
+	return t.mods
+}
+
+// update compares the keys held down now with the last update and queues events for
+// the differences.  Which key typed which char can't be told, so the first char typed
+// is only given to a key if it's the one key that types chars to go down or repeat,
+// and any other chars get KeyOther events.
+func (t *TKeyboard) update(in Input) {
+	chars := []rune{}
+	for _, r := range in.AppendInputChars(nil) {
+		if r != 0 {
+			chars = append(chars, r)
+		}
+	}
+	mods := heldModifiers(in)
+	t.muEvents.Lock()
+	defer t.muEvents.Unlock()
+	t.mods = mods
+	events := []KeyboardEvent{}
+	typing := -1 // Index of the key that typed a char, or -2 if there's more than one
+	for k := Key(0); k < keyCount; k++ {
+		d := in.KeyPressDuration(k)
+		e := KeyboardEvent{Key: k, ScanCode: scanCodes[k], Modifiers: mods}
+		switch {
+		case d > 0 && !t.held[k]:
+			e.Action = KeyPressed
+		case d == 0 && t.held[k]:
+			e.Action = KeyReleased
+		case d > 1 && repeatingKeyPressed(in, k):
+			e.Action = KeyRepeated
+		default:
+			continue
+		}
+		t.held[k] = d > 0
+		if e.Action != KeyReleased && typesChar(k) {
+			if typing == -1 {
+				typing = len(events)
+			} else {
+				typing = -2
+			}
+		}
+		events = append(events, e)
+	}
+	if typing >= 0 && len(chars) > 0 {
+		events[typing].Rune, chars = chars[0], chars[1:]
+	}
+	for _, e := range events {
+		t.queue(e)
+	}
+	for _, r := range chars {
+		t.queue(KeyboardEvent{Action: KeyPressed, Key: KeyOther, Rune: r, Modifiers: mods})
+	}
+}
+
+// queue adds e to the events, dropping the oldest if there are too many.
+func (t *TKeyboard) queue(e KeyboardEvent) {
+	if len(t.events) >= maxKeyboardEvents {
+		t.events = t.events[1:]
+	}
+	t.events = append(t.events, e)
+}
+
+// typesChar returns true if key types a char.
+func typesChar(key Key) bool {
+	if key == KeySpace {
+		return true
+	}
+	for _, k := range charKeys {
+		if k.key == key {
+			return true
+		}
+	}
+	return false
+}
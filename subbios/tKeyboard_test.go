@@ -0,0 +1,73 @@
+package subbios
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestKeyboard(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	k := &s.TKeyboard
+	frames := []testInput{
+		{map[Key]int{KeyShift: 1}, ""},
+		{map[Key]int{KeyShift: 2, KeyA: 1}, "A"},
+		{map[Key]int{KeyShift: 3, KeyA: 30}, "A!"},
+		{map[Key]int{KeyA: 31}, ""},
+		{map[Key]int{}, ""},
+		{pressed(KeyA, KeyB), "ab"},
+	}
+	for _, frame := range frames {
+		k.update(frame)
+		if frame.keys[KeyA] > 0 != k.IsKeyHeld(KeyA) {
+			t.Errorf("IsKeyHeld(KeyA) is %v with A held for %d", k.IsKeyHeld(KeyA), frame.keys[KeyA])
+		}
+	}
+	want := []KeyboardEvent{
+		{KeyPressed, KeyShift, 0x2a, 0, ModShift},
+		{KeyPressed, KeyA, 0x1e, 'A', ModShift},
+		{KeyRepeated, KeyA, 0x1e, 'A', ModShift},
+		{KeyPressed, KeyOther, 0, '!', ModShift},
+		{KeyReleased, KeyShift, 0x2a, 0, 0},
+		{KeyReleased, KeyA, 0x1e, 0, 0},
+		// Which of two keys typed which char isn't known
+		{KeyPressed, KeyA, 0x1e, 0, 0},
+		{KeyPressed, KeyB, 0x30, 0, 0},
+		{KeyPressed, KeyOther, 0, 'a', 0},
+		{KeyPressed, KeyOther, 0, 'b', 0},
+	}
+	for i, w := range want {
+		if got, ok := k.NextEvent(); !ok || got != w {
+			t.Errorf("event %d is %+v, want %+v", i, got, w)
+		}
+	}
+	if e, ok := k.NextEvent(); ok {
+		t.Errorf("got an extra event %+v", e)
+	}
+}
+
+func TestKeyboardGoroutines(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	k := &s.TKeyboard
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i <= 100; i++ {
+			k.update(testInput{map[Key]int{KeySpace: i % 2}, ""})
+		}
+	}()
+	n := 0
+	for n < 100 {
+		k.WaitEvent()
+		n++
+		k.IsKeyHeld(KeySpace)
+	}
+	wg.Wait()
+	if e, ok := k.NextEvent(); ok {
+		t.Errorf("got an extra event %+v", e)
+	}
+}
@@ -0,0 +1,312 @@
+package subbios
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// TSound has the sound functions attached to it.  It drives an emulation of the
+// Nimbus's three-voice sound chip (an AY-3-8910): three square wave voices, a noise
+// generator that can be mixed into any of them and an envelope generator that can
+// control their volumes.  You can poke the chip's registers directly or use the
+// friendlier functions.  If the backend can play audio (the Ebiten one can) the
+// sound comes out of the speakers, otherwise you can render it to a WAV.
+type TSound struct {
+	s                      *Subbios
+	psg                    *psg
+	music                  *music
+	audible                bool      // Set to true if the backend is playing the sound
+	lastUpdate             time.Time // When update last moved FPlay on, if it's not audible
+	SuppressCtrlCInterrupt bool      // Set to true to stop CTRL-C interrupting PLAY (you can still check the status though).
+	muCtrlCInterrupt       sync.Mutex
+	ctrlCInterrupt         bool
+}
+
+// AudioBackend is implemented by backends that can play sound.  PlayAudio is called
+// once by InitWithBackend with an endless stream of 16-bit signed little-endian
+// stereo samples at SampleRate, and returns an error if it can't play it.
+type AudioBackend interface {
+	PlayAudio(stream io.Reader) error
+}
+
+// FSoundColdStart silences all the voices and puts the sound chip back the way it
+// was at power on.
+func (t *TSound) FSoundColdStart() {
+	t.s.setFunctionError(t.SoundColdStart())
+}
+
+// SoundColdStart is the error-returning variant of FSoundColdStart.
+func (t *TSound) SoundColdStart() error {
+	t.psg.mu.Lock()
+	defer t.psg.mu.Unlock()
+	t.psg.reset()
+	return nil
+}
+
+// FWriteSoundRegister writes value (0-255) to one of the sound chip's registers
+// (0-13), just like poking the real thing:
+// 0-5 - tone period of voices 0-2, fine then coarse (12 bits per voice).
+// 6 - noise period (5 bits).
+// 7 - mixer: bits 0-2 switch the tone of voices 0-2 off, bits 3-5 switch the noise off.
+// 8-10 - volume of voices 0-2 (4 bits), or set bit 4 to use the envelope instead.
+// 11-12 - envelope period, fine then coarse (16 bits).
+// 13 - envelope shape (4 bits).  Writing this restarts the envelope.
+func (t *TSound) FWriteSoundRegister(register, value int) {
+	t.s.setFunctionError(t.WriteSoundRegister(register, value))
+}
+
+// WriteSoundRegister is the error-returning variant of FWriteSoundRegister.
+func (t *TSound) WriteSoundRegister(register, value int) error {
+	if register < 0 || register > 13 {
+		return invalidParameter("WriteSoundRegister", "register", register)
+	}
+	if value < 0 || value > 255 {
+		return invalidParameter("WriteSoundRegister", "value", value)
+	}
+	t.psg.mu.Lock()
+	defer t.psg.mu.Unlock()
+	t.psg.writeRegister(register, uint8(value))
+	return nil
+}
+
+// FReadSoundRegister returns the value of one of the sound chip's registers (0-13).
+func (t *TSound) FReadSoundRegister(register int) (value int) {
+	value, err := t.ReadSoundRegister(register)
+	t.s.setFunctionError(err)
+	return value
+}
+
+// ReadSoundRegister is the error-returning variant of FReadSoundRegister.
+func (t *TSound) ReadSoundRegister(register int) (value int, err error) {
+	if register < 0 || register > 13 {
+		return 0, invalidParameter("ReadSoundRegister", "register", register)
+	}
+	t.psg.mu.Lock()
+	defer t.psg.mu.Unlock()
+	return int(t.psg.regs[register]), nil
+}
+
+// FSetTone sets the tone period of a voice (0-2).  period is 1-4095 and the
+// frequency of the tone is 125000 / period Hz, so 284 is roughly the A above
+// middle C.
+func (t *TSound) FSetTone(voice, period int) {
+	t.s.setFunctionError(t.SetTone(voice, period))
+}
+
+// SetTone is the error-returning variant of FSetTone.
+func (t *TSound) SetTone(voice, period int) error {
+	if voice < 0 || voice > 2 {
+		return invalidParameter("SetTone", "voice", voice)
+	}
+	if period < 1 || period > 4095 {
+		return invalidParameter("SetTone", "period", period)
+	}
+	t.psg.mu.Lock()
+	defer t.psg.mu.Unlock()
+	t.psg.writeRegister(psgToneFineA+voice*2, uint8(period&0xff))
+	t.psg.writeRegister(psgToneCoarseA+voice*2, uint8(period>>8))
+	return nil
+}
+
+// FSetNoise sets the period of the noise generator (1-31).  Lower is hissier.
+func (t *TSound) FSetNoise(period int) {
+	t.s.setFunctionError(t.SetNoise(period))
+}
+
+// SetNoise is the error-returning variant of FSetNoise.
+func (t *TSound) SetNoise(period int) error {
+	if period < 1 || period > 31 {
+		return invalidParameter("SetNoise", "period", period)
+	}
+	t.psg.mu.Lock()
+	defer t.psg.mu.Unlock()
+	t.psg.writeRegister(psgNoisePeriod, uint8(period))
+	return nil
+}
+
+// FSetMixer switches the tone and noise of a voice (0-2) on or off.  With both off
+// the voice just outputs its volume, which isn't much use unless you're changing it
+// very quickly.
+func (t *TSound) FSetMixer(voice int, tone, noise bool) {
+	t.s.setFunctionError(t.SetMixer(voice, tone, noise))
+}
+
+// SetMixer is the error-returning variant of FSetMixer.
+func (t *TSound) SetMixer(voice int, tone, noise bool) error {
+	if voice < 0 || voice > 2 {
+		return invalidParameter("SetMixer", "voice", voice)
+	}
+	t.psg.mu.Lock()
+	defer t.psg.mu.Unlock()
+	mixer := t.psg.regs[psgMixer] | (1 << voice) | (8 << voice) // Off is 1
+	if tone {
+		mixer &^= 1 << voice
+	}
+	if noise {
+		mixer &^= 8 << voice
+	}
+	t.psg.writeRegister(psgMixer, mixer)
+	return nil
+}
+
+// FSetVolume sets the volume of a voice (0-2).  volume is 0-15, where 0 is silent,
+// and is ignored if useEnvelope is true, in which case the envelope controls the
+// volume instead.
+func (t *TSound) FSetVolume(voice, volume int, useEnvelope bool) {
+	t.s.setFunctionError(t.SetVolume(voice, volume, useEnvelope))
+}
+
+// SetVolume is the error-returning variant of FSetVolume.
+func (t *TSound) SetVolume(voice, volume int, useEnvelope bool) error {
+	if voice < 0 || voice > 2 {
+		return invalidParameter("SetVolume", "voice", voice)
+	}
+	if volume < 0 || volume > 15 {
+		return invalidParameter("SetVolume", "volume", volume)
+	}
+	if useEnvelope {
+		volume |= 0x10
+	}
+	t.psg.mu.Lock()
+	defer t.psg.mu.Unlock()
+	t.psg.writeRegister(psgAmplitudeA+voice, uint8(volume))
+	return nil
+}
+
+// FSetEnvelope sets the envelope period and shape, and (re)starts the envelope.
+// period is 1-65535, and each of the 16 steps of the envelope lasts period * 8
+// microseconds.  shape is 0-15 and is made up of these bits:
+// 8 - continue: if not set the envelope goes through one cycle then drops to 0.
+// 4 - attack: the envelope starts by going up rather than down.
+// 2 - alternate: the envelope changes direction after each cycle.
+// 1 - hold: the envelope stops after the first cycle.
+// e.g. 0 is a single decay, 8 is a repeating sawtooth and 14 is a triangle wave.
+func (t *TSound) FSetEnvelope(period, shape int) {
+	t.s.setFunctionError(t.SetEnvelope(period, shape))
+}
+
+// SetEnvelope is the error-returning variant of FSetEnvelope.
+func (t *TSound) SetEnvelope(period, shape int) error {
+	if period < 1 || period > 65535 {
+		return invalidParameter("SetEnvelope", "period", period)
+	}
+	if shape < 0 || shape > 15 {
+		return invalidParameter("SetEnvelope", "shape", shape)
+	}
+	t.psg.mu.Lock()
+	defer t.psg.mu.Unlock()
+	t.psg.writeRegister(psgEnvelopeFine, uint8(period&0xff))
+	t.psg.writeRegister(psgEnvelopeCoarse, uint8(period>>8))
+	t.psg.writeRegister(psgEnvelopeShape, uint8(shape))
+	return nil
+}
+
+// Stream returns an endless stream of the sound chip's output, with any music from
+// FPlay mixed in, as 16-bit signed little-endian stereo samples at SampleRate.  The
+// sound only moves on as the stream is read, so only one thing should read it:
+// InitWithBackend already hands it to the backend if it's an AudioBackend.
+func (t *TSound) Stream() io.Reader {
+	return soundStream{p: t.psg, m: t.music}
+}
+
+// RenderWAV runs the sound chip (and FPlay) for duration d and returns what it played as a WAV
+// file.  This moves the chip on just like playing it would (envelopes progress and
+// so on), so it's meant for headless use and tests where nothing else is reading
+// the Stream.  A negative d is an invalid parameter.
+func (t *TSound) RenderWAV(d time.Duration) ([]byte, error) {
+	if d < 0 {
+		return nil, invalidParameter("RenderWAV", "d", d)
+	}
+	samples := int(d * SampleRate / time.Second)
+	pcm := make([]byte, samples*4)
+	t.Stream().Read(pcm)
+	var buf bytes.Buffer
+	if err := writeWAV(&buf, pcm); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FPlay plays a tune written in the music macro language of BASIC's PLAY command, e.g.
+// "T150 L8 O3 CDEFG4 P4 >C4".  It returns straight away and the tune plays in the
+// background after anything that's already playing.  See parseMusic for the whole
+// language.  If the string has a mistake in it nothing is played.
+func (t *TSound) FPlay(mml string) {
+	t.s.setFunctionError(t.Play(mml))
+}
+
+// Play is the error-returning variant of FPlay.  The error says where the mistake is.
+func (t *TSound) Play(mml string) error {
+	notes, err := parseMusic(mml)
+	if err != nil {
+		return err
+	}
+	t.music.add(notes)
+	return nil
+}
+
+// IsPlaying returns true if FPlay still has notes to play.  If the backend can't play
+// audio the tune moves on in real time as Update is called, as if it could.
+func (t *TSound) IsPlaying() bool {
+	return t.music.notesLeft() > 0
+}
+
+// NotesLeft returns the number of notes and rests FPlay still has to play, including
+// the one playing now, like PLAY(n) in BASIC.
+func (t *TSound) NotesLeft() int {
+	return t.music.notesLeft()
+}
+
+// StopPlaying stops FPlay and forgets any notes still to play.
+func (t *TSound) StopPlaying() {
+	t.music.stop()
+}
+
+// Bell rings the bell, like printing BEL (\x07) does.  It's a short beep if the
+// backend is playing sound, otherwise (no sound or it couldn't be started) the
+// border flashes instead.
+func (t *TSound) Bell() {
+	if t.audible {
+		t.music.beep()
+		return
+	}
+	t.s.TGraphicsOutput.v.visualBell()
+}
+
+// update moves FPlay on by the time since the last update if the backend isn't
+// reading the Stream, so the tune still finishes.
+func (t *TSound) update() {
+	now := time.Now()
+	if !t.lastUpdate.IsZero() {
+		t.music.skip(int(now.Sub(t.lastUpdate) * SampleRate / time.Second))
+	}
+	t.lastUpdate = now
+}
+
+// GetCtrlCInterrupt checks if CTRL+C has been pressed since the flag was last unset,
+// which also stops FPlay unless SuppressCtrlCInterrupt is true.  Passing true will
+// unset the flag after checking, otherwise the flag remains unchanged.
+func (t *TSound) GetCtrlCInterrupt(unsetAfterChecking bool) bool {
+	t.muCtrlCInterrupt.Lock()
+	defer t.muCtrlCInterrupt.Unlock()
+	val := t.ctrlCInterrupt
+	if unsetAfterChecking {
+		t.ctrlCInterrupt = false
+	}
+	return val
+}
+
+// checkKeyboardInterrupts sets the CTRL+C flag and stops FPlay if CTRL+C is pressed.
+func (t *TSound) checkKeyboardInterrupts(in Input) {
+	if !ctrlCPressed(in) {
+		return
+	}
+	t.muCtrlCInterrupt.Lock()
+	t.ctrlCInterrupt = true
+	t.muCtrlCInterrupt.Unlock()
+	if !t.SuppressCtrlCInterrupt {
+		t.StopPlaying()
+	}
+}
@@ -0,0 +1,166 @@
+package subbios
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/adamstimb/nimgobus/subbios/colour"
+)
+
+// samples returns the left channel of a WAV made by RenderWAV.
+func samples(t *testing.T, wav []byte) []int16 {
+	t.Helper()
+	if string(wav[0:4]) != "RIFF" || string(wav[8:12]) != "WAVE" || string(wav[36:40]) != "data" {
+		t.Fatalf("not a WAV")
+	}
+	pcm := wav[44:]
+	s := []int16{}
+	for i := 0; i+3 < len(pcm); i += 4 {
+		s = append(s, int16(binary.LittleEndian.Uint16(pcm[i:])))
+	}
+	return s
+}
+
+func TestSoundTone(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	s.TSound.FSetTone(1, 284) // 440 Hz
+	s.TSound.FSetMixer(1, true, false)
+	s.TSound.FSetVolume(1, 15, false)
+	if s.FunctionError != 0 {
+		t.Fatalf("FunctionError = %d", s.FunctionError)
+	}
+	wav, err := s.TSound.RenderWAV(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := samples(t, wav)
+	if len(got) != SampleRate {
+		t.Fatalf("got %d samples, want %d", len(got), SampleRate)
+	}
+	// Count rising edges
+	rising := 0
+	for i := 1; i < len(got); i++ {
+		if got[i-1] < 5000 && got[i] >= 5000 {
+			rising++
+		}
+	}
+	if rising < 438 || rising > 442 {
+		t.Errorf("got %d cycles in a second, want 440", rising)
+	}
+	if _, err := s.TSound.RenderWAV(-time.Second); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("RenderWAV(-1s) error = %v, want ErrInvalidParameter", err)
+	}
+}
+
+func TestSoundEnvelope(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	s.TSound.FSetMixer(0, false, false) // Just the volume
+	s.TSound.FSetVolume(0, 0, true)
+	s.TSound.FSetEnvelope(1250, 0) // Decay over 160 ms
+	got := samples(t, mustRender(t, &s, 200*time.Millisecond))
+	if got[0] < 10000 {
+		t.Errorf("envelope should start loud, got %d", got[0])
+	}
+	if got[SampleRate/20] >= got[0] {
+		t.Errorf("envelope should decay, got %d then %d", got[0], got[SampleRate/20])
+	}
+	if got[len(got)-1] != 0 {
+		t.Errorf("envelope should end silent, got %d", got[len(got)-1])
+	}
+	// Shape 13 goes up and stays there
+	s.TSound.FSetEnvelope(10, 13)
+	got = samples(t, mustRender(t, &s, 10*time.Millisecond))
+	if got[len(got)-1] != 10922 {
+		t.Errorf("envelope should hold at full volume, got %d", got[len(got)-1])
+	}
+}
+
+func TestSoundErrors(t *testing.T) {
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	if err := s.TSound.SetTone(3, 100); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("SetTone(3, 100) error = %v, want ErrInvalidParameter", err)
+	}
+	if err := s.TSound.WriteSoundRegister(14, 0); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("WriteSoundRegister(14, 0) error = %v, want ErrInvalidParameter", err)
+	}
+	s.TSound.FWriteSoundRegister(1, 0xff)
+	if v := s.TSound.FReadSoundRegister(1); v != 0x0f {
+		t.Errorf("coarse tone register = %#x, want 0x0f", v)
+	}
+}
+
+func mustRender(t *testing.T, s *Subbios, d time.Duration) []byte {
+	t.Helper()
+	wav, err := s.TSound.RenderWAV(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wav
+}
+
+// audioBackend is an ImageBackend that says it can play sound.
+type audioBackend struct {
+	*ImageBackend
+	stream io.Reader
+}
+
+func (a *audioBackend) PlayAudio(stream io.Reader) error {
+	a.stream = stream
+	return nil
+}
+
+// mutedBackend is an ImageBackend that can't start its sound.
+type mutedBackend struct {
+	*ImageBackend
+}
+
+func (m mutedBackend) PlayAudio(io.Reader) error { return errors.New("no sound device") }
+
+func TestBell(t *testing.T) {
+	// Headless so the border flashes
+	b := NewImageBackend(nil)
+	s := Subbios{}
+	s.InitWithBackend(b)
+	s.Stdio.Printf("\x07")
+	s.Update()
+	img := b.Image().(*image.RGBA)
+	flash := colour.PhysicalColours[15-colour.DefaultLowResColours[0].FirstPhysicalColour]
+	if c := img.RGBAAt(0, 0); c != flash {
+		t.Errorf("border pixel is %v, expected %v", c, flash)
+	}
+	if row, col := s.Stdio.GetCurpos(); row != 1 || col != 1 {
+		t.Errorf("cursor moved to %d, %d", row, col)
+	}
+	s.Close()
+	// And if the sound couldn't be started
+	m := mutedBackend{NewImageBackend(nil)}
+	s = Subbios{}
+	s.InitWithBackend(m)
+	s.Stdio.Printf("\x07")
+	s.Update()
+	img = m.Image().(*image.RGBA)
+	if c := img.RGBAAt(0, 0); c != flash {
+		t.Errorf("border pixel is %v with no sound, expected %v", c, flash)
+	}
+	s.Close()
+	// With sound it beeps
+	a := &audioBackend{ImageBackend: NewImageBackend(nil)}
+	s = Subbios{}
+	s.InitWithBackend(a)
+	t.Cleanup(s.Close)
+	s.Stdio.Printf("\x07")
+	got := samples(t, mustRender(t, &s, 200*time.Millisecond))
+	if got[100] == 0 || got[len(got)-1] != 0 {
+		t.Errorf("expected a short beep, got %d then %d", got[100], got[len(got)-1])
+	}
+}
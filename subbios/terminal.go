@@ -0,0 +1,253 @@
+package subbios
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+// terminalRows is the height of the terminal, which is always the whole screen.
+const terminalRows = 25
+
+// terminalKeys are the sequences a terminal sends for keys that aren't chars, with
+// the application cursor keys version (ESC[?1h) if there is one.  They're the ones in
+// the ansi terminfo entry, or the xterm ones if it hasn't got them.
+var terminalKeys = []struct {
+	key    Key
+	seq    string
+	appSeq string
+}{
+	{KeyEnter, "\r", ""},
+	{KeyBackspace, "\b", ""},
+	{KeyTab, "\t", ""},
+	{KeyEscape, "\x1b", ""},
+	{KeyUp, "\x1b[A", "\x1bOA"},
+	{KeyDown, "\x1b[B", "\x1bOB"},
+	{KeyRight, "\x1b[C", "\x1bOC"},
+	{KeyLeft, "\x1b[D", "\x1bOD"},
+	{KeyHome, "\x1b[H", "\x1bOH"},
+	{KeyEnd, "\x1b[F", "\x1bOF"},
+	{KeyInsert, "\x1b[L", ""},
+	{KeyDelete, "\x1b[3~", ""},
+	{KeyPageUp, "\x1b[5~", ""},
+	{KeyPageDown, "\x1b[6~", ""},
+	{KeyF1, "\x1bOP", ""},
+	{KeyF2, "\x1bOQ", ""},
+	{KeyF3, "\x1bOR", ""},
+	{KeyF4, "\x1bOS", ""},
+	{KeyF5, "\x1b[15~", ""},
+	{KeyF6, "\x1b[17~", ""},
+	{KeyF7, "\x1b[18~", ""},
+	{KeyF8, "\x1b[19~", ""},
+	{KeyF9, "\x1b[20~", ""},
+	{KeyF10, "\x1b[21~", ""},
+	{KeyF11, "\x1b[23~", ""},
+	{KeyF12, "\x1b[24~", ""},
+}
+
+// RunTerminal runs cmd on a pseudo-terminal in the console, like a little terminal
+// emulator, and returns when it finishes with the error from cmd.Wait.  Whatever the
+// program prints goes through the escape sequence engine in ANSI mode, and keys
+// typed go to the program instead of the keyboard buffer (so CTRL+C goes to the
+// program too).  The terminal is the whole screen, 80x25 or 40x25 depending on the
+// column mode, and TERM is set to ansi unless cmd.Env already sets it, so things like
+// less and top know what to send.  Afterwards the scrolling area is left as the whole
+// screen and the console goes back to the escape sequences it was using before.
+// Only Linux has pseudo-terminals we know how to drive: anywhere else this returns
+// EFuncNotImplemented.
+func (sio *Stdio) RunTerminal(cmd *exec.Cmd) error {
+	c := sio.c
+	ptmx, tty, err := openPTY()
+	if err != nil {
+		return err
+	}
+	defer ptmx.Close()
+
+	// Make the whole screen an ANSI terminal
+	ansiMode := c.ansiMode
+	cursorDisplayed := c.cursorDisplayed
+	c.ansiMode = true
+	c.scrollingArea = [4]int{1, 1, terminalRows, c.v.screenWidth}
+	c.curpos = [2]int{1, 1}
+	cols := c.v.screenWidth
+	if err := setWinsize(ptmx, terminalRows, cols); err != nil {
+		tty.Close()
+		return err
+	}
+	cmd.Env = terminalEnv(cmd.Env)
+	err = startOnPTY(cmd, tty)
+	tty.Close() // The program has its own copy now
+	if err != nil {
+		c.ansiMode = ansiMode
+		return err
+	}
+	c.setTerminal(ptmx)
+	defer func() {
+		c.setTerminal(nil)
+		c.ansiMode = ansiMode
+		c.setAppCursorKeys(false)
+		c.cursorDisplayed = cursorDisplayed
+	}()
+
+	// Pipe output into the console until the program closes the terminal
+	buf := make([]byte, 4096)
+	pending := []byte{}
+	for {
+		n, err := ptmx.Read(buf)
+		if n > 0 {
+			pending = append(pending, buf[:n]...)
+			pending = sio.terminalOutput(pending)
+			// The program might have changed the column mode
+			if c.v.screenWidth != cols {
+				cols = c.v.screenWidth
+				c.scrollingArea = [4]int{1, 1, terminalRows, cols}
+				setWinsize(ptmx, terminalRows, cols)
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	return cmd.Wait()
+}
+
+// terminalOutput sends as much of b to the console as it can and returns the rest,
+// which is an escape sequence or UTF-8 char that hasn't all arrived yet.
+func (sio *Stdio) terminalOutput(b []byte) []byte {
+	n := completeOutput(b)
+	for s := b[:n]; len(s) > 0; {
+		r, size := utf8.DecodeRune(s)
+		sio.c.stdoutBuffer.Enqueue(r)
+		s = s[size:]
+	}
+	sio.c.flushStdoutBuffer()
+	return append([]byte{}, b[n:]...)
+}
+
+// completeOutput returns how many bytes at the start of b can be sent to the console
+// without splitting an escape sequence or UTF-8 char.  If something's been
+// incomplete for a silly length of time it's sent anyway.
+func completeOutput(b []byte) int {
+	const maxPending = 256
+	n := len(b)
+	// Incomplete UTF-8 char
+	for i := n - 1; i >= 0 && i >= n-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				n = i
+			}
+			break
+		}
+	}
+	// Incomplete escape sequence
+	esc := strings.LastIndexByte(string(b[:n]), '\x1b')
+	if esc == -1 || n-esc > maxPending || escapeComplete(b[esc:n]) {
+		return n
+	}
+	return esc
+}
+
+// escapeComplete returns true if b, which starts with ESC, has a whole escape
+// sequence in it.
+func escapeComplete(b []byte) bool {
+	if len(b) < 2 {
+		return false
+	}
+	switch b[1] {
+	case '[':
+		for _, ch := range b[2:] {
+			// Final byte (~ is left out because Nimbus sequences have it in the middle)
+			if ch >= 0x40 && ch <= 0x7d {
+				return true
+			}
+		}
+		return false
+	case ']':
+		return strings.Contains(string(b), "\x07") || strings.Contains(string(b), "\x1b\\")
+	case '(', ')', '*', '+':
+		return len(b) >= 3
+	}
+	return true
+}
+
+// terminalEnv returns the environment for a program run by RunTerminal: TERM=ansi is
+// added to env unless it has a TERM already, and a nil env means the current
+// environment, with its TERM replaced.
+func terminalEnv(env []string) []string {
+	if env == nil {
+		for _, e := range os.Environ() {
+			if !strings.HasPrefix(e, "TERM=") {
+				env = append(env, e)
+			}
+		}
+	}
+	for _, e := range env {
+		if strings.HasPrefix(e, "TERM=") {
+			return env
+		}
+	}
+	return append(env, "TERM=ansi")
+}
+
+// terminalInput returns what the keys pressed since the last update should send to a
+// terminal.
+func terminalInput(in Input, appCursorKeys bool) []byte {
+	b := []byte{}
+	if in.KeyPressDuration(KeyControl) > 0 {
+		// Chars don't come through while CTRL is held, so CTRL+A to CTRL+Z are sent
+		// as \x01 to \x1a
+		for _, k := range charKeys {
+			if k.char >= 'A' && k.char <= 'Z' && repeatingKeyPressed(in, k.key) {
+				b = append(b, byte(k.char-'A'+1))
+			}
+		}
+		return b
+	}
+	for _, k := range terminalKeys {
+		if !repeatingKeyPressed(in, k.key) {
+			continue
+		}
+		if appCursorKeys && k.appSeq != "" {
+			b = append(b, k.appSeq...)
+		} else {
+			b = append(b, k.seq...)
+		}
+	}
+	for _, r := range in.AppendInputChars(nil) {
+		if r != 0 {
+			b = utf8.AppendRune(b, r)
+		}
+	}
+	return b
+}
+
+// setTerminal sends keys to w instead of the stdin buffer, or back to the stdin
+// buffer if w is nil.
+func (c *console) setTerminal(w io.Writer) {
+	c.muTerminal.Lock()
+	defer c.muTerminal.Unlock()
+	c.terminal = w
+}
+
+// setAppCursorKeys sets whether the terminal gets the application cursor keys.
+func (c *console) setAppCursorKeys(on bool) {
+	c.muTerminal.Lock()
+	defer c.muTerminal.Unlock()
+	c.appCursorKeys = on
+}
+
+// sendToTerminal sends any keys that have been pressed to the terminal and returns
+// true, or returns false if there isn't a terminal running.
+func (c *console) sendToTerminal(in Input) bool {
+	c.muTerminal.Lock()
+	defer c.muTerminal.Unlock()
+	if c.terminal == nil {
+		return false
+	}
+	if b := terminalInput(in, c.appCursorKeys); len(b) > 0 {
+		c.terminal.Write(b)
+	}
+	return true
+}
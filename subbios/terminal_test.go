@@ -0,0 +1,103 @@
+package subbios
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestTerminalInput(t *testing.T) {
+	tests := []struct {
+		in            testInput
+		appCursorKeys bool
+		want          string
+	}{
+		{testInput{chars: "ls -l£"}, false, "ls -l£"},
+		{testInput{keys: pressed(KeyEnter)}, false, "\r"},
+		{testInput{keys: pressed(KeyUp)}, false, "\x1b[A"},
+		{testInput{keys: pressed(KeyUp)}, true, "\x1bOA"},
+		{testInput{keys: pressed(KeyPageDown, KeyF12)}, true, "\x1b[6~\x1b[24~"},
+		{testInput{keys: pressed(KeyControl, KeyC), chars: "c"}, false, "\x03"},
+		{testInput{keys: pressed(KeyControl, KeyA)}, false, "\x01"},
+		{testInput{keys: pressed(KeyControl, KeyZ)}, false, "\x1a"},
+		{testInput{keys: pressed(KeyControl, Key1)}, false, ""},
+	}
+	for _, test := range tests {
+		if got := string(terminalInput(test.in, test.appCursorKeys)); got != test.want {
+			t.Errorf("terminalInput(%v, %v) = %q, want %q", test.in, test.appCursorKeys, got, test.want)
+		}
+	}
+}
+
+func TestCompleteOutput(t *testing.T) {
+	tests := []struct {
+		b    string
+		want int
+	}{
+		{"hello", 5},
+		{"hello\x1b", 5},
+		{"hello\x1b[3", 5},
+		{"hello\x1b[?25", 5},
+		{"hello\x1b[?25l", 11},
+		{"hello\x1b]0;title", 5},
+		{"hello\x1b]0;title\x07", 15},
+		{"hello\x1b(", 5},
+		{"hello\x1b(B", 8},
+		{"hello\xc2", 5},
+		{"hello\xc2\xa3", 7},
+	}
+	for _, test := range tests {
+		if got := completeOutput([]byte(test.b)); got != test.want {
+			t.Errorf("completeOutput(%q) = %d, want %d", test.b, got, test.want)
+		}
+	}
+}
+
+func TestRunTerminal(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("terminals need Linux")
+	}
+	s := Subbios{}
+	s.Init()
+	t.Cleanup(s.Close)
+	s.Stdio.Printf("\x1b~F") // Hide the cursor, which should still be hidden afterwards
+	out := filepath.Join(t.TempDir(), "out")
+	cmd := exec.Command("sh", "-c", `stty size > "$0"; echo $TERM >> "$0"; read line; echo "$line" >> "$0"; printf '\033[31mdone'`, out)
+	done := make(chan error)
+	go func() { done <- s.Stdio.RunTerminal(cmd) }()
+
+	// Type a line once the terminal's running
+	for !s.Stdio.c.sendToTerminal(testInput{chars: "hi"}) {
+		time.Sleep(time.Millisecond)
+	}
+	s.Stdio.c.sendToTerminal(testInput{keys: pressed(KeyEnter)})
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RunTerminal returned %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("RunTerminal didn't return")
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "25 80\nansi\nhi\n"; string(got) != want {
+		t.Errorf("program wrote %q, want %q", got, want)
+	}
+	// The line typed is echoed, so done is on the next row
+	if row, col := s.Stdio.GetCurpos(); row != 2 || col != 5 {
+		t.Errorf("cursor at %d, %d after printing done, want 2, 5", row, col)
+	}
+	if s.Stdio.c.cursorDisplayed {
+		t.Errorf("cursor displayed after RunTerminal, want it hidden like before")
+	}
+	if s.Stdio.c.ansiMode || s.Stdio.c.sendToTerminal(testInput{}) {
+		t.Errorf("console still in terminal mode")
+	}
+}
@@ -3,14 +3,14 @@ package subbios
 import (
 	"image"
 	"image/color"
+	"image/draw"
 	"math"
 	"sync"
 	"time"
 
 	"github.com/adamstimb/nimgobus/internal/make2darray"
-	"github.com/adamstimb/nimgobus/internal/subbios/colour"
+	"github.com/adamstimb/nimgobus/subbios/colour"
 	"github.com/adamstimb/nimgobus/sprite"
-	"github.com/hajimehoshi/ebiten/v2"
 )
 
 // FillStyle describes the fill settings for AREA, FLOOD, CIRCLE, and SLICE
@@ -36,6 +36,7 @@ type feature struct {
 	saveTable                   *sprite.SaveTable
 	overrideCurrentClippingArea bool
 	clippingArea                int
+	areaCopy                    *areaCopy // If set then copy an area of video memory instead of drawing pixels
 }
 
 // Coord defines an x, y coordinate
@@ -54,9 +55,10 @@ type clippingArea struct {
 
 // video holds all the video processing malarky.
 type video struct {
-	monitor              *ebiten.Image       // The display image
-	screenImage          *ebiten.Image       // The video memory converted into an Ebiten image
-	borderImage          *ebiten.Image       // The background/border image on which the screenImage is drawn
+	backend              Backend             // Where the monitor image gets sent
+	headless             bool                // Set to true if there's no game loop to flush the draw queue
+	monitorImage         *image.RGBA         // The display image, i.e. the border with the screenImage drawn on it
+	screenImage          *image.RGBA         // The video memory converted into an image
 	borderSize           int                 // The border size/thickness
 	borderColour         int                 // The current border colour
 	clippingAreaTable    [10]clippingArea    // A table of clipping areas
@@ -76,12 +78,19 @@ type video struct {
 	memory               [250][640]int       // The video memory, a 640x250 array of integers represents the logical colours of each pixel
 	muVideoMemoryOverlay sync.Mutex          //
 	videoMemoryOverlay   [250][640]int       // A copy of the video memory where temporal things like cursors can be drawn
+	muColourFlashCounter sync.Mutex          //
 	colourFlashCounter   int                 // This counter is used to time fast and slow flashing colours
+	done                 chan struct{}       // Closed by Subbios.Close to stop colourFlashTicker
 	muHoldDrawQueue      sync.Mutex          //
 	holdDrawQueue        bool                // This flag will pause updateVideoMemory() if set to true
 	muDrawQueue          sync.Mutex          //
 	drawQueue            []feature           // A queue of features to be written to video memory
 	con                  *console            // Connect the console here
+	muRecording          sync.Mutex          //
+	recording            *recording          // The screen recording in progress, if any
+	muVisualBell         sync.Mutex          //
+	visualBellUntil      time.Time           // The border flashes until this time for the visual bell
+	visualBellPending    bool                // Set to true until the visual bell has been drawn at least once
 	logo                 [][]int             // RM Nimbus branding
 }
 
@@ -93,17 +102,33 @@ func (v *video) advanceLineStyleCounter() {
 	}
 }
 
-// colourFlashTicker increments the colourFlash counter every 500 ms
-func (v *video) colourFlashTicker() {
+// colourFlashTicker increments the colourFlash counter every 250 ms until done is
+// closed
+func (v *video) colourFlashTicker(done <-chan struct{}) {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
 	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		v.muColourFlashCounter.Lock()
 		v.colourFlashCounter++
 		if v.colourFlashCounter > 4 {
 			v.colourFlashCounter = 0
 		}
-		time.Sleep(250 * time.Millisecond)
+		v.muColourFlashCounter.Unlock()
 	}
 }
 
+// getColourFlashCounter returns the colourFlash counter
+func (v *video) getColourFlashCounter() int {
+	v.muColourFlashCounter.Lock()
+	defer v.muColourFlashCounter.Unlock()
+	return v.colourFlashCounter
+}
+
 // purgeDrawQueue empties the draw queue
 func (v *video) purgeDrawQueue() {
 	v.muDrawQueue.Lock()
@@ -111,9 +136,14 @@ func (v *video) purgeDrawQueue() {
 	v.muDrawQueue.Unlock()
 }
 
-// waitForEmptyDrawQueue waits until the draw queue is empty
+// waitForEmptyDrawQueue waits until the draw queue is empty.  If headless there's
+// nothing else to empty it so it's flushed here and now, unless it's being held, in
+// which case it waits for it to be let go just like it would with a game loop.
 func (v *video) waitForEmptyDrawQueue() {
 	for {
+		if v.headless && !v.drawQueueHeld() {
+			v.flushDrawQueue()
+		}
 		v.muDrawQueue.Lock()
 		if len(v.drawQueue) == 0 {
 			v.muDrawQueue.Unlock()
@@ -123,6 +153,13 @@ func (v *video) waitForEmptyDrawQueue() {
 	}
 }
 
+// drawQueueHeld returns true if holdDrawQueue is set.
+func (v *video) drawQueueHeld() bool {
+	v.muHoldDrawQueue.Lock()
+	defer v.muHoldDrawQueue.Unlock()
+	return v.holdDrawQueue
+}
+
 // resetVideoMemory wipes the video memory (set all pixels to 0)
 func (v *video) resetVideoMemory() {
 	v.muMemory.Lock()
@@ -253,25 +290,32 @@ func (v *video) initPolymarkers() {
 	v.polymarkers = append(v.polymarkers, colour.DefaultPolymarkers...)
 }
 
-// handleFlash is a helper function for renderScreenImage to handle flashing colours
-func (v *video) handleFlash(x, y int) color.RGBA {
+// handleFlash is a helper function for renderScreenImage to handle flashing colours.  It
+// returns the physical colour of a logical colour in the given flash phase.
+func (v *video) handleFlash(logicalColour int, phase FlashPhase) color.RGBA {
 	// On start-up the colour lookup table may not be initialized in time so return black
-	if len(v.colourLookupTable) == 0 {
+	if logicalColour < 0 || logicalColour >= len(v.colourLookupTable) {
 		return colour.PhysicalColours[0]
 	}
-	cltElement := v.colourLookupTable[v.videoMemoryOverlay[y][x]] // Assumes not flashing at first
+	cltElement := v.colourLookupTable[logicalColour] // Assumes not flashing at first
 	col := colour.PhysicalColours[cltElement.FirstPhysicalColour]
-	switch cltElement.FlashSpeed {
+	if phase == FlashSecond && cltElement.FlashSpeed != colour.NoFlash {
+		return colour.PhysicalColours[cltElement.SecondPhysicalColour]
+	}
+	if phase != FlashCurrent {
+		return col
+	}
+	switch flashCounter := v.getColourFlashCounter(); cltElement.FlashSpeed {
 	case 1:
 		// Slow flash
-		if v.colourFlashCounter == 0 || v.colourFlashCounter == 1 {
+		if flashCounter == 0 || flashCounter == 1 {
 			col = colour.PhysicalColours[cltElement.FirstPhysicalColour]
 		} else {
 			col = colour.PhysicalColours[cltElement.SecondPhysicalColour]
 		}
 	case 2:
 		// Fast flash
-		if v.colourFlashCounter == 0 || v.colourFlashCounter == 2 {
+		if flashCounter == 0 || flashCounter == 2 {
 			col = colour.PhysicalColours[cltElement.FirstPhysicalColour]
 		} else {
 			col = colour.PhysicalColours[cltElement.SecondPhysicalColour]
@@ -281,21 +325,25 @@ func (v *video) handleFlash(x, y int) color.RGBA {
 }
 
 // renderScreenImage converts video memory into an image.
-// This function assumes the drawQueue is locked before being called!
 func (v *video) renderScreenImage() {
-	// Define a new image and colourises it according to video memory overlay
-	img := image.NewRGBA(image.Rect(0, 0, 640, 500))
+	// Colourise the screen image according to video memory overlay
+	v.drawScreen(v.screenImage, &v.videoMemoryOverlay, FlashCurrent)
+}
+
+// drawScreen colourises a 640x500 image according to mem, scaling it up to keep the
+// aspect ratio of the current screen mode.
+func (v *video) drawScreen(img *image.RGBA, mem *[250][640]int, phase FlashPhase) {
 	imgX := 0
 	imgY := 0
 	if v.screenWidth == 40 {
 		// low-res render
 		for memX := 0; memX < 320; memX++ {
 			for memY := 0; memY < 250; memY++ {
-				col := v.handleFlash(memX, memY)
-				img.Set(imgX, imgY, col)
-				img.Set(imgX+1, imgY, col)
-				img.Set(imgX+1, imgY+1, col)
-				img.Set(imgX, imgY+1, col)
+				col := v.handleFlash(mem[memY][memX], phase)
+				img.SetRGBA(imgX, imgY, col)
+				img.SetRGBA(imgX+1, imgY, col)
+				img.SetRGBA(imgX+1, imgY+1, col)
+				img.SetRGBA(imgX, imgY+1, col)
 				imgY = imgY + 2
 			}
 			imgX = imgX + 2
@@ -305,29 +353,56 @@ func (v *video) renderScreenImage() {
 		// high-res render
 		for memX := 0; memX < 640; memX++ {
 			for memY := 0; memY < 250; memY++ {
-				col := v.handleFlash(memX, memY)
-				img.Set(imgX, imgY, col)
-				img.Set(imgX, imgY+1, col)
+				col := v.handleFlash(mem[memY][memX], phase)
+				img.SetRGBA(imgX, imgY, col)
+				img.SetRGBA(imgX, imgY+1, col)
 				imgY = imgY + 2
 			}
 			imgX = imgX + 1
 			imgY = 0
 		}
 	}
-	v.screenImage = ebiten.NewImageFromImage(img)
 }
 
-// renderMonitor draws the final monitor image to be displayed by Ebiten
-func (v *video) renderMonitor() {
+// drawMonitor draws the border on img with the screen image on top, in the opposite
+// colour if bell is set
+func (v *video) drawMonitor(img, screen *image.RGBA, bell bool) {
 	// Render border (Todo: only fill when border colour changes)
-	v.borderImage.Fill(colour.PhysicalColours[colour.DefaultLowResColours[v.borderColour].FirstPhysicalColour]) // Border colour does not flash and cannot be alterned by CLT
-	// Draw screenImage on border image
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(float64(v.borderSize), float64(v.borderSize))
-	v.borderImage.DrawImage(v.screenImage, op)
-	// Draw border image on monitor
-	op = &ebiten.DrawImageOptions{}
-	v.monitor.DrawImage(v.borderImage, op)
+	physicalColour := colour.DefaultLowResColours[v.borderColour].FirstPhysicalColour // Border colour does not flash and cannot be alterned by CLT
+	if bell {
+		physicalColour = 15 - physicalColour // Flash the opposite colour for the visual bell
+	}
+	border := colour.PhysicalColours[physicalColour]
+	draw.Draw(img, img.Bounds(), &image.Uniform{border}, image.Point{}, draw.Src)
+	// Draw screenImage on border
+	r := screen.Bounds().Add(image.Pt(v.borderSize, v.borderSize))
+	draw.Draw(img, r, screen, image.Point{}, draw.Src)
+}
+
+// visualBell flashes the border for a moment, and for at least one frame however long
+// the next one takes to come.
+func (v *video) visualBell() {
+	v.muVisualBell.Lock()
+	v.visualBellUntil = time.Now().Add(150 * time.Millisecond)
+	v.visualBellPending = true
+	v.muVisualBell.Unlock()
+}
+
+// visualBellShowing returns true if the border should be flashing for the visual bell
+// on this frame, and counts the frame as having shown it.
+func (v *video) visualBellShowing() bool {
+	v.muVisualBell.Lock()
+	defer v.muVisualBell.Unlock()
+	showing := v.visualBellPending || time.Now().Before(v.visualBellUntil)
+	v.visualBellPending = false
+	return showing
+}
+
+// renderMonitor draws the final monitor image and sends it to the backend
+func (v *video) renderMonitor() {
+	v.drawMonitor(v.monitorImage, v.screenImage, v.visualBellShowing())
+	v.captureFrame()
+	v.backend.Render(v.monitorImage)
 }
 
 // Update should be called on each Ebiten Update call
@@ -417,6 +492,11 @@ func (v *video) writeFeature(f feature) {
 		}
 		clip = clippingArea{0, 0, maxX, 250}
 	}
+	// Copying an area is a different kettle of fish
+	if f.areaCopy != nil {
+		v.copyArea(f.areaCopy, clip)
+		return
+	}
 	// redraw saveTable data and then update it if it's a sprite being moved
 	if f.isSprite && f.isMoveSprite {
 		// draw saveTable
@@ -565,30 +645,42 @@ func (v *video) resizeFeature(f feature, newWidth, newHeight int) feature {
 	return feature{pixels: newImg, x: f.x, y: f.y, colour: f.colour, xor: f.xor}
 }
 
+// flushDrawQueue writes all the features in the drawQueue to video memory and
+// empties the queue.
+func (v *video) flushDrawQueue() {
+	v.muDrawQueue.Lock()
+	v.writeDrawQueue()
+	v.muDrawQueue.Unlock()
+}
+
+// writeDrawQueue is flushDrawQueue for when muDrawQueue is already locked.
+func (v *video) writeDrawQueue() {
+	for _, f := range v.drawQueue {
+		v.writeFeature(f)
+	}
+	v.drawQueue = []feature{}
+}
+
 // updateVideoMemory writes all sprites in the drawQueue to video memory
 func (v *video) updateVideoMemory() {
 	// Skip if holdDrawQueue is true
-	v.muHoldDrawQueue.Lock()
-	if v.holdDrawQueue {
-		v.muHoldDrawQueue.Unlock()
+	if v.drawQueueHeld() {
 		return
 	}
-	v.muHoldDrawQueue.Unlock()
-	// Write all the features in the draw queue
+	// Write all the features in the draw queue and update the video overlay in one
+	// go, so nothing can be drawn in between
 	v.muDrawQueue.Lock()
-	for _, f := range v.drawQueue {
-		v.writeFeature(f)
-	}
-	// update video overlay
-	for y := 0; y < 250; y++ {
-		v.videoMemoryOverlay[y] = v.memory[y]
-	}
-	// draw cursor on overlay if enabled
-	if v.con.cursorDisplayed {
+	v.writeDrawQueue()
+	v.muMemory.Lock()
+	v.muVideoMemoryOverlay.Lock()
+	v.videoMemoryOverlay = v.memory
+	v.muVideoMemoryOverlay.Unlock()
+	v.muMemory.Unlock()
+	// draw the scrollback or the cursor on overlay if enabled
+	if !v.con.drawScrollback() && v.con.cursorDisplayed {
 		v.drawCursor()
 	}
-	// flush drawQueue and render screen image
-	v.drawQueue = []feature{}
+	// render screen image
 	v.muDrawQueue.Unlock()
 	v.renderScreenImage()
 }
@@ -635,7 +727,8 @@ func (v *video) makeConsoleCharImg(c, fg, bg, charset int, underline bool) [][]i
 }
 
 // plonkChar is called by the console to render an ASCII char on the screen
-func (v *video) plonkChar(c, x, y, fg, bg, charset int, xor, underline bool) {
+func (v *video) plonkChar(c, x, y, fg, bg, charset int, xor, underline bool, attributes charAttributes) {
+	fg, bg = attributes.colours(fg, bg, v.screenWidth)
 	img := v.makeConsoleCharImg(c, fg, bg, charset, underline)
 	v.drawFeature(feature{pixels: img, x: x, y: y, colour: -1, xor: xor, isConsoleText: true})
 }
@@ -657,7 +750,7 @@ func (v *video) drawCursor() {
 	}
 
 	// Flashing cursor
-	if v.con.cursorFlashing && (v.colourFlashCounter < 2) {
+	if v.con.cursorFlashing && (v.getColourFlashCounter() < 2) {
 		v.writeFeatureToOverlay(feature{pixels: img, x: x, y: y, colour: -1, xor: true})
 	}
 }